@@ -0,0 +1,65 @@
+package log
+
+import (
+	"errors"
+	"time"
+)
+
+// errBackoff is returned while waiting for the next reconnection.
+var errBackoff = errors.New("reconnection backoff")
+
+// reconnectBackoff is the exponential backoff between reconnections, which doubles from min up to
+// max on each failure.
+type reconnectBackoff struct {
+	min, max time.Duration
+	backoff  time.Duration
+	nextDial time.Time
+}
+
+// newReconnectBackoff creates a reconnectBackoff from the bounds in milliseconds, default as 100
+// and 30000.
+func newReconnectBackoff(minMs, maxMs int) reconnectBackoff {
+	b := reconnectBackoff{min: msOrDefault(minMs, 100), max: msOrDefault(maxMs, 30000)}
+	if b.max < b.min {
+		b.max = b.min
+	}
+	return b
+}
+
+// wait returns errBackoff until the next reconnection is allowed.
+func (b *reconnectBackoff) wait() error {
+	if time.Now().Before(b.nextDial) {
+		return errBackoff
+	}
+	return nil
+}
+
+// fail doubles the backoff before the next reconnection.
+func (b *reconnectBackoff) fail() {
+	if b.backoff == 0 {
+		b.backoff = b.min
+	} else if b.backoff *= 2; b.backoff > b.max {
+		b.backoff = b.max
+	}
+	b.nextDial = time.Now().Add(b.backoff)
+}
+
+// reset resets the backoff after a successful connection.
+func (b *reconnectBackoff) reset() {
+	b.backoff = 0
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+// Close implements io.Closer.
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func msOrDefault(ms, def int) time.Duration {
+	if ms <= 0 {
+		ms = def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,275 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchConfig is the batching config shared by the remote writers, which is set in remote_config.
+type BatchConfig struct {
+	// QueueSize is the size of the queue of entries waiting to be batched, default as 10000.
+	// Entries are dropped when the queue is full.
+	QueueSize int `yaml:"queue_size"`
+	// WriteLogSize is the threshold in bytes to send a batch, default as 4K.
+	WriteLogSize int `yaml:"write_log_size"`
+	// BatchSize is the max number of entries in a batch, default as 0 which means no limit.
+	BatchSize int `yaml:"batch_size"`
+	// WriteLogInterval is the interval in milliseconds to send a batch, default as 100ms.
+	WriteLogInterval int `yaml:"write_log_interval"`
+	// BufferSize is the max size in bytes of the entries kept in memory while they can not be
+	// sent, default as 4M. Entries are dropped when the buffer is full.
+	BufferSize int `yaml:"buffer_size"`
+}
+
+func (c *BatchConfig) setDefaults() {
+	if c.QueueSize <= 0 {
+		c.QueueSize = 10000
+	}
+	if c.WriteLogSize <= 0 {
+		c.WriteLogSize = 4 * 1024
+	}
+	if c.WriteLogInterval <= 0 {
+		c.WriteLogInterval = 100
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 4 * 1024 * 1024
+	}
+}
+
+// batchWriter is a zapcore.WriteSyncer which collects entries in the background like
+// rollwriter.AsyncRollWriter, and sends them in batches. Entries which fail to be sent are kept
+// in a bounded buffer and sent again with the next batch.
+type batchWriter struct {
+	name string // name of the writer in reports, like "net writer tcp://127.0.0.1:5170"
+	cfg  BatchConfig
+	// send sends a batch of entries. It returns the number of leading entries sent, which are
+	// removed from the buffer, and the error if not all of them are sent. If the error is a
+	// *discardError, the whole batch is removed from the buffer and the entries it counts are
+	// reported as dropped.
+	send func(entries [][]byte) (int, error)
+
+	queue    chan []byte
+	sync     chan struct{}
+	syncErr  chan error
+	close    chan struct{}
+	closeErr chan error
+	done     chan struct{}
+	once     sync.Once
+
+	dropped  uint64     // accessed atomically
+	reportMu sync.Mutex // reports are made by the background goroutine, or by Write once closed
+	reported uint64     // dropped count already reported
+
+	// owned by the background goroutine.
+	pending     [][]byte
+	pendingSize int
+}
+
+func newBatchWriter(name string, cfg BatchConfig, send func([][]byte) (int, error)) *batchWriter {
+	cfg.setDefaults()
+	w := &batchWriter{
+		name:     name,
+		cfg:      cfg,
+		send:     send,
+		queue:    make(chan []byte, cfg.QueueSize),
+		sync:     make(chan struct{}),
+		syncErr:  make(chan error),
+		close:    make(chan struct{}),
+		closeErr: make(chan error),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues an entry. It implements io.Writer. The entry is dropped if the queue is full or the
+// writer is closed.
+func (w *batchWriter) Write(data []byte) (int, error) {
+	select {
+	case <-w.done:
+		atomic.AddUint64(&w.dropped, 1)
+		w.report()
+		return len(data), nil
+	default:
+	}
+	entry := make([]byte, len(data))
+	copy(entry, data)
+	select {
+	case w.queue <- entry:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	return len(data), nil
+}
+
+// Sync sends the queued entries. It implements zapcore.WriteSyncer.
+func (w *batchWriter) Sync() error {
+	select {
+	case w.sync <- struct{}{}:
+		return <-w.syncErr
+	case <-w.done:
+		return nil
+	}
+}
+
+// Close sends the queued entries and stops the writer. It implements io.Closer.
+func (w *batchWriter) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.close)
+		err = <-w.closeErr
+	})
+	return err
+}
+
+func (w *batchWriter) run() {
+	ticker := time.NewTicker(time.Millisecond * time.Duration(w.cfg.WriteLogInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = w.flush()
+		case entry := <-w.queue:
+			w.buffer(entry)
+			if w.pendingSize >= w.cfg.WriteLogSize || w.full() {
+				_ = w.flush()
+			}
+		case <-w.sync:
+			w.drain()
+			w.syncErr <- w.flush()
+		case <-w.close:
+			w.drain()
+			err := w.flush()
+			close(w.done)
+			w.discard()
+			w.closeErr <- err
+			return
+		}
+	}
+}
+
+// discard drops the entries left in the buffer and the queue when the writer is closed, and
+// reports them. Entries queued later are dropped by Write.
+func (w *batchWriter) discard() {
+	n := len(w.pending)
+	w.pending, w.pendingSize = nil, 0
+	for {
+		select {
+		case <-w.queue:
+			n++
+		default:
+			atomic.AddUint64(&w.dropped, uint64(n))
+			w.report()
+			return
+		}
+	}
+}
+
+// drain moves all queued entries into the buffer.
+func (w *batchWriter) drain() {
+	for {
+		select {
+		case entry := <-w.queue:
+			w.buffer(entry)
+		default:
+			return
+		}
+	}
+}
+
+// buffer appends an entry to the buffer, or drops it if the buffer is full.
+func (w *batchWriter) buffer(entry []byte) {
+	if w.pendingSize+len(entry) > w.cfg.BufferSize {
+		atomic.AddUint64(&w.dropped, 1)
+		return
+	}
+	w.pending = append(w.pending, entry)
+	w.pendingSize += len(entry)
+}
+
+// flush sends the buffer in batches of WriteLogSize bytes, and stops at the first error.
+func (w *batchWriter) flush() error {
+	for len(w.pending) > 0 {
+		n, size := 0, 0
+		for n < len(w.pending) && (n == 0 || size+len(w.pending[n]) <= w.cfg.WriteLogSize) &&
+			(w.cfg.BatchSize <= 0 || n < w.cfg.BatchSize) {
+			size += len(w.pending[n])
+			n++
+		}
+		sent, err := w.send(w.pending[:n])
+		e, discard := err.(*discardError)
+		if discard {
+			atomic.AddUint64(&w.dropped, uint64(e.n))
+			sent = n
+		}
+		for _, entry := range w.pending[:sent] {
+			w.pendingSize -= len(entry)
+		}
+		w.pending = w.pending[sent:]
+		if discard {
+			w.report()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(w.pending) == 0 {
+		w.pending = nil
+	}
+	w.report()
+	return nil
+}
+
+// full reports whether the buffer holds BatchSize entries.
+func (w *batchWriter) full() bool {
+	return w.cfg.BatchSize > 0 && len(w.pending) >= w.cfg.BatchSize
+}
+
+// discardError is returned by send when a batch, or some entries of it, can never be sent, so
+// that they are dropped.
+type discardError struct {
+	err error
+	n   int // number of entries dropped
+}
+
+// Error implements error.
+func (e *discardError) Error() string {
+	return e.err.Error()
+}
+
+// Dropped returns the number of entries dropped so far.
+func (w *batchWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// droppedHook holds the func(writer string, n uint64) set by SetDroppedHook.
+var droppedHook atomic.Value
+
+// SetDroppedHook sets the hook called with the name of a remote writer, like
+// "net writer tcp://127.0.0.1:5170", and the number of entries it dropped since the last call,
+// such as to count them in metrics. It is called by the background goroutine of the writer when
+// the drops are reported to stderr. A nil hook removes it.
+func SetDroppedHook(hook func(writer string, n uint64)) {
+	droppedHook.Store(hook)
+}
+
+// report prints the number of entries dropped since the last report to stderr, and passes it to
+// the hook set by SetDroppedHook. Entries dropped while the remote end is unavailable are
+// reported once it is back.
+func (w *batchWriter) report() {
+	w.reportMu.Lock()
+	defer w.reportMu.Unlock()
+	dropped := w.Dropped()
+	if dropped == w.reported {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "log: %s dropped %d entries\n", w.name, dropped-w.reported)
+	if hook, _ := droppedHook.Load().(func(string, uint64)); hook != nil {
+		hook(w.name, dropped-w.reported)
+	}
+	w.reported = dropped
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Color modes of FormatConfig.Color.
+const (
+	// ColorAuto colors logs if the output is a terminal and NO_COLOR is not set. Terminals are
+	// only detected for the console and stderr writers by os.Stdout and os.Stderr.
+	ColorAuto = "auto"
+	// ColorAlways always colors logs.
+	ColorAlways = "always"
+	// ColorNever never colors logs, which is the default.
+	ColorNever = "never"
+)
+
+// ANSI escape codes used by colored output.
+const (
+	colorRed     = 31
+	colorYellow  = 33
+	colorBlue    = 34
+	colorMagenta = 35
+	colorCyan    = 36
+	colorDim     = 2
+)
+
+var levelColors = map[zapcore.Level]int{
+	ZapTraceLevel:       colorCyan,
+	zapcore.DebugLevel:  colorMagenta,
+	zapcore.InfoLevel:   colorBlue,
+	zapcore.WarnLevel:   colorYellow,
+	zapcore.ErrorLevel:  colorRed,
+	zapcore.DPanicLevel: colorRed,
+	zapcore.PanicLevel:  colorRed,
+	zapcore.FatalLevel:  colorRed,
+}
+
+// colorize wraps s in the ANSI escape code.
+func colorize(s string, code int) string {
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", code, s)
+}
+
+// CapitalColorLevelEncoder serializes a Level to an all-caps string and adds color, such as "TRACE"
+// in cyan or "ERROR" in red.
+func CapitalColorLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	colorLevelEncoder(CapitalLevelEncoder)(l, enc)
+}
+
+// LowercaseColorLevelEncoder serializes a Level to a lowercase string and adds color, such as
+// "trace" in cyan or "error" in red.
+func LowercaseColorLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	colorLevelEncoder(LowercaseLevelEncoder)(l, enc)
+}
+
+// colorLevelEncoder wraps a level encoder to add color by level.
+func colorLevelEncoder(encode zapcore.LevelEncoder) zapcore.LevelEncoder {
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		arr := &arrayEncoder{}
+		encode(l, arr)
+		s := textValue(arr.single())
+		if c, ok := levelColors[l]; ok {
+			s = colorize(s, c)
+		}
+		enc.AppendString(s)
+	}
+}
+
+// dimTimeEncoder wraps a time encoder to print dimmed time.
+func dimTimeEncoder(encode zapcore.TimeEncoder) zapcore.TimeEncoder {
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		arr := &arrayEncoder{}
+		encode(t, arr)
+		enc.AppendString(colorize(textValue(arr.single()), colorDim))
+	}
+}
+
+// highlightCallerEncoder wraps a caller encoder to print highlighted caller.
+func highlightCallerEncoder(encode zapcore.CallerEncoder) zapcore.CallerEncoder {
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		arr := &arrayEncoder{}
+		encode(caller, arr)
+		enc.AppendString(colorize(textValue(arr.single()), colorCyan))
+	}
+}
+
+// colorEnabled decides whether the output should be colored. In auto mode, only console or stderr
+// outputs to a terminal are colored, and the NO_COLOR environment variable disables colors.
+func colorEnabled(c *OutputConfig) bool {
+	switch c.FormatConfig.Color {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		if os.Getenv("NO_COLOR") != "" {
+			return false
+		}
+		switch c.Writer {
+		case OutputConsole:
+			return isTerminal(os.Stdout)
+		case OutputStderr:
+			return isTerminal(os.Stderr)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return st.Mode()&os.ModeCharDevice != 0
+}
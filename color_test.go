@@ -0,0 +1,117 @@
+package log_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCapitalColorLevelEncoder(t *testing.T) {
+	cfg := zapcore.EncoderConfig{LevelKey: "L", EncodeLevel: log.CapitalColorLevelEncoder}
+	buf, err := zapcore.NewConsoleEncoder(cfg).EncodeEntry(zapcore.Entry{Level: log.ZapTraceLevel}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[36mTRACE\x1b[0m\n", buf.String())
+	buf, err = zapcore.NewConsoleEncoder(cfg).EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[31mERROR\x1b[0m\n", buf.String())
+}
+
+func TestColorConfig(t *testing.T) {
+	ent := newTestEntry()
+	encode := func(c *log.OutputConfig) string {
+		cfg := log.NewEncoderConfig(c)
+		cfg.TimeKey, cfg.NameKey, cfg.MessageKey = "", "", ""
+		buf, err := zapcore.NewConsoleEncoder(cfg).EncodeEntry(ent, nil)
+		assert.Nil(t, err)
+		return buf.String()
+	}
+
+	c := &log.OutputConfig{Writer: "console"}
+	assert.Equal(t, "INFO\ttlog/server.go:42\n", encode(c))
+
+	c.FormatConfig.Color = log.ColorAlways
+	assert.Equal(t, "\x1b[34mINFO\x1b[0m\ttlog/server.go:42\n", encode(c))
+
+	c.FormatConfig.DimTime, c.FormatConfig.HighlightCaller = true, true
+	assert.Equal(t, "\x1b[34mINFO\x1b[0m\t\x1b[36mtlog/server.go:42\x1b[0m\n", encode(c))
+	cfg := log.NewEncoderConfig(c)
+	buf, err := zapcore.NewConsoleEncoder(cfg).EncodeEntry(ent, nil)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "\x1b[2m2006-01-02 15:04:05.000\x1b[0m")
+
+	// the output of go test is not a terminal.
+	c.FormatConfig.Color = log.ColorAuto
+	assert.Equal(t, "INFO\ttlog/server.go:42\n", encode(c))
+
+	c.FormatConfig.Color = log.ColorNever
+	assert.Equal(t, "INFO\ttlog/server.go:42\n", encode(c))
+}
+
+func TestColorAutoNoColor(t *testing.T) {
+	old, ok := os.LookupEnv("NO_COLOR")
+	defer func() {
+		if ok {
+			os.Setenv("NO_COLOR", old)
+		} else {
+			os.Unsetenv("NO_COLOR")
+		}
+	}()
+	os.Setenv("NO_COLOR", "1")
+	cfg := log.NewEncoderConfig(&log.OutputConfig{
+		Writer:       "console",
+		FormatConfig: log.FormatConfig{Color: log.ColorAuto},
+	})
+	buf, err := zapcore.NewConsoleEncoder(cfg).EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel}, nil)
+	assert.Nil(t, err)
+	assert.NotContains(t, buf.String(), "\x1b[")
+}
+
+func TestColorInvalid(t *testing.T) {
+	for _, color := range []string{"yes", "Always", "true"} {
+		assert.Panics(t, func() {
+			log.NewZapLog([]log.OutputConfig{{Writer: "console", FormatConfig: log.FormatConfig{Color: color}}})
+		}, color)
+	}
+}
+
+func TestColorAutoSplitConsole(t *testing.T) {
+	old, ok := os.LookupEnv("NO_COLOR")
+	defer func() {
+		if ok {
+			os.Setenv("NO_COLOR", old)
+		}
+	}()
+	os.Unsetenv("NO_COLOR")
+
+	// the null device is a character device, which is detected as a terminal.
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	defer null.Close()
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	assert.Nil(t, err)
+
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = null, stderr
+	logger := log.NewZapLog([]log.OutputConfig{{
+		Writer:       "console",
+		Level:        "debug",
+		WriteConfig:  log.WriteConfig{StderrLevel: "error"},
+		FormatConfig: log.FormatConfig{Color: log.ColorAuto},
+		Formatter:    "pattern",
+	}})
+	logger.Info("to stdout")
+	logger.Error("to stderr")
+	os.Stdout, os.Stderr = oldStdout, oldStderr
+	assert.Nil(t, stderr.Close())
+
+	data, err := ioutil.ReadFile(stderr.Name())
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "ERROR")
+	assert.NotContains(t, string(data), "\x1b[")
+}
@@ -1,15 +1,36 @@
 package log
 
 import (
+	"io"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// output name, default support console and file.
+// output name, default support console, stderr, file, writer, syslog, net, http, loki,
+// elasticsearch, fluentd, otlp, journald and memory.
 const (
-	OutputConsole = "console"
-	OutputFile    = "file"
+	OutputConsole       = "console"
+	OutputStderr        = "stderr"
+	OutputFile          = "file"
+	OutputWriter        = "writer"
+	OutputSyslog        = "syslog"
+	OutputNet           = "net"
+	OutputHTTP          = "http"
+	OutputLoki          = "loki"
+	OutputElasticsearch = "elasticsearch"
+	OutputFluentd       = "fluentd"
+	OutputOTLP          = "otlp"
+	OutputJournald      = "journald"
+	OutputMemory        = "memory"
+)
+
+// formatter name, default support console, json, logfmt and pattern.
+const (
+	FormatterConsole = "console"
+	FormatterJSON    = "json"
+	FormatterLogfmt  = "logfmt"
+	FormatterPattern = "pattern"
 )
 
 // Config is the log config. Each log may have multiple outputs.
@@ -17,11 +38,16 @@ type Config []OutputConfig
 
 // OutputConfig is the output config, includes console, file and remote.
 type OutputConfig struct {
+	// Name is the optional name of the output, which may be used to address the output in
+	// SetLevel and GetLevel instead of its index.
+	Name string `yaml:"name"`
+
 	// Writer is the output of log, such as console or file.
 	Writer      string
 	WriteConfig WriteConfig `yaml:"writer_config"`
 
-	// Formatter is the format of log, such as console or json.
+	// Formatter is the format of log, such as console, json, logfmt or pattern. Formatters may be
+	// extended by RegisterFormatter.
 	Formatter    string
 	FormatConfig FormatConfig `yaml:"formatter_config"`
 
@@ -31,12 +57,47 @@ type OutputConfig struct {
 
 	// Level controls the log level, like debug, info or error.
 	Level string
+	// MaxLevel is the max level of logs written to the output, like info for an output without
+	// warnings and errors. Empty means no limit.
+	MaxLevel string `yaml:"max_level"`
+	// LevelSet lists the only levels written to the output, like [warn, error]. It is exclusive with
+	// MaxLevel, and logs below Level are still filtered out.
+	LevelSet []string `yaml:"levels"`
 
 	// CallerSkip controls the nesting depth of log function.
 	CallerSkip int `yaml:"caller_skip"`
+
+	// Fields are static fields attached to every log of the output, such as service or hostname.
+	// They only apply to this output, so fields shared by all outputs are repeated in each of them.
+	// The placeholders ${hostname}, ${pid}, ${env:VAR} and ${env:VAR:-default} in values are
+	// expanded at setup time, and an unset VAR without default fails the setup.
+	Fields map[string]string `yaml:"fields"`
+
+	// Route selects the logs written to the output by their fields, message and logger name, such
+	// as audit logs for an audit file. All logs are written if it is empty.
+	Route RouteConfig `yaml:"route"`
+}
+
+// RouteConfig is the route config of an output. A log is written to the output if it matches any
+// of the include rules, or there are no include rules, and matches none of the exclude rules.
+type RouteConfig struct {
+	Include []RouteRule `yaml:"include"`
+	Exclude []RouteRule `yaml:"exclude"`
 }
 
-// WriteConfig is the local file config.
+// RouteRule matches a log if all of its conditions which are set are met.
+type RouteRule struct {
+	// Fields are the fields the log must carry, like category: audit. An empty value matches any
+	// value of the key. The static fields of the output are not matched.
+	Fields map[string]string `yaml:"fields"`
+	// Message is the regular expression the message must match.
+	Message string `yaml:"message"`
+	// Logger is the name of the logger created by Named, which also matches its descendants, like
+	// grpc for grpc.client.
+	Logger string `yaml:"logger"`
+}
+
+// WriteConfig is the local file and console config.
 type WriteConfig struct {
 	// LogPath is the log path like /usr/local/tlog/log/.
 	LogPath string `yaml:"log_path"`
@@ -58,6 +119,14 @@ type WriteConfig struct {
 	// TimeUnit splits files by time unit, like year/month/hour/minute, default day.
 	// It takes effect only when split by time.
 	TimeUnit TimeUnit `yaml:"time_unit"`
+
+	// StderrLevel splits console output by level: logs at or above it are written to stderr, and the
+	// others to stdout. Empty means all logs are written to stdout.
+	StderrLevel string `yaml:"stderr_level"`
+
+	// Writer is the destination of the writer output, which can only be set programmatically, such
+	// as a bytes.Buffer or a pipe. If it is a zapcore.WriteSyncer, its Sync is called on Sync.
+	Writer io.Writer `yaml:"-"`
 }
 
 // FormatConfig is the log format config.
@@ -80,6 +149,34 @@ type FormatConfig struct {
 	MessageKey string `yaml:"message_key"`
 	// StackTraceKey is the stack trace key of log output, default as "S".
 	StacktraceKey string `yaml:"stacktrace_key"`
+
+	// Pattern is the line template of the pattern formatter, such as
+	// "%time [%-5level] %caller %msg %fields", default as DefaultPattern on empty.
+	// See NewPatternEncoder for the directives.
+	Pattern string `yaml:"pattern"`
+
+	// LevelEncoder is the level format, one of capital/lowercase/color/lowercase_color, default as
+	// capital on empty.
+	LevelEncoder string `yaml:"level_encoder"`
+	// CallerEncoder is the caller format, short for package/file:line or full for the full path,
+	// default as short on empty.
+	CallerEncoder string `yaml:"caller_encoder"`
+	// DurationEncoder is the duration format, one of string/seconds/millis/nanos, default as string
+	// on empty.
+	DurationEncoder string `yaml:"duration_encoder"`
+	// TimeZone is the time zone of the time of log output, "UTC", "Local" or an IANA name like
+	// "Asia/Shanghai", default as local on empty. It applies to both default and custom time_fmt.
+	TimeZone string `yaml:"time_zone"`
+
+	// Color controls level coloring, one of auto/always/never, default as never on empty.
+	// Auto colors console output only if stdout is a terminal, and stderr output only if stderr
+	// is, when NO_COLOR is not set. The other writers, like file or an io.Writer output, are
+	// never colored by auto, even if they write to a terminal.
+	Color string `yaml:"color"`
+	// DimTime prints dimmed time when colored.
+	DimTime bool `yaml:"dim_time"`
+	// HighlightCaller prints highlighted caller when colored.
+	HighlightCaller bool `yaml:"highlight_caller"`
 }
 
 // WriteMode is the log write mode, one of 1, 2, 3.
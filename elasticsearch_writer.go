@@ -0,0 +1,241 @@
+package log
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hyperits/tlog/plugin"
+	"github.com/lestrrat-go/strftime"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultElasticsearchIndex is the index of the elasticsearch writer when it is not configured.
+const DefaultElasticsearchIndex = "logs-%Y.%m.%d"
+
+// ElasticsearchConfig is the config of the elasticsearch writer, which is set in remote_config.
+// The url of HTTPClientConfig is the base url of the cluster, or of an index, which "/_bulk" is
+// appended to unless it already ends with it.
+type ElasticsearchConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	BatchConfig      `yaml:",inline"`
+
+	// Index is the index name templated by the time of entries in strftime format, like
+	// logs-%Y.%m.%d, default as DefaultElasticsearchIndex. The time is in the time zone of the
+	// formatter config.
+	Index string `yaml:"index"`
+	// Username and Password are the credentials of basic auth.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// ElasticsearchWriterFactory is the elasticsearch writer instance Factory.
+type ElasticsearchWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *ElasticsearchWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers elasticsearch output writer. Documents are encoded by the
+// json formatter.
+func (f *ElasticsearchWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("elasticsearch writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("elasticsearch writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	if f := GetLogEncoderKey(FormatterJSON, cfg.Formatter); f != FormatterJSON {
+		return fmt.Errorf("elasticsearch writer formatter %s invalid, only json is supported", f)
+	}
+	cfg.Formatter = FormatterJSON
+	ec := &ElasticsearchConfig{}
+	if err := decodeRemoteConfig(cfg, ec); err != nil {
+		return fmt.Errorf("elasticsearch writer remote config invalid: %v", err)
+	}
+	index, err := strftime.New(GetLogEncoderKey(DefaultElasticsearchIndex, ec.Index))
+	if err != nil {
+		return fmt.Errorf("elasticsearch writer index invalid: %v", err)
+	}
+
+	clientCfg := ec.HTTPClientConfig
+	if u, err := url.Parse(clientCfg.URL); err == nil {
+		if path := strings.TrimRight(u.Path, "/"); !strings.HasSuffix(path, "/_bulk") {
+			u.Path = path + "/_bulk"
+			clientCfg.URL = u.String()
+		}
+	}
+	if ec.Username != "" {
+		headers := map[string]string{"Authorization": "Basic " +
+			base64.StdEncoding.EncodeToString([]byte(ec.Username+":"+ec.Password))}
+		for k, v := range clientCfg.Headers {
+			headers[k] = v
+		}
+		clientCfg.Headers = headers
+	}
+	client, err := newHTTPClient(&clientCfg)
+	if err != nil {
+		return fmt.Errorf("elasticsearch writer %v", err)
+	}
+	s := &elasticsearchSender{client: client}
+	w := newBatchWriter("elasticsearch writer "+clientCfg.URL, ec.BatchConfig, s.send)
+
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core = &elasticsearchCore{
+		LevelEnabler: lvl,
+		enc:          newEncoder(cfg),
+		w:            w,
+		index:        index,
+		loc:          timeZone(cfg),
+	}
+	decoder.ZapLevel = lvl
+	decoder.Closer = closerFunc(func() error {
+		client.close()
+		return w.Close()
+	})
+	return nil
+}
+
+// elasticsearchCore is a zapcore.Core which queues each entry as the action and the document of a
+// bulk request.
+type elasticsearchCore struct {
+	zapcore.LevelEnabler
+	enc   zapcore.Encoder
+	w     *batchWriter
+	index *strftime.Strftime
+	loc   *time.Location
+}
+
+// With implements zapcore.Core.
+func (c *elasticsearchCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *elasticsearchCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *elasticsearchCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	action, err := json.Marshal(map[string]map[string]string{
+		"create": {"_index": c.index.FormatString(ent.Time.In(c.loc))},
+	})
+	if err != nil {
+		return err
+	}
+	entry := make([]byte, 0, len(action)+buf.Len()+2)
+	entry = append(entry, action...)
+	entry = append(entry, '\n')
+	entry = append(entry, bytes.TrimRight(buf.Bytes(), "\r\n")...)
+	entry = append(entry, '\n')
+	_, err = c.w.Write(entry)
+	return err
+}
+
+// Sync implements zapcore.Core.
+func (c *elasticsearchCore) Sync() error {
+	return c.w.Sync()
+}
+
+// elasticsearchBulkResponse is the response of the bulk API.
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// elasticsearchSender sends batches of entries queued by elasticsearchCore as bulk requests.
+type elasticsearchSender struct {
+	client *httpClient
+}
+
+// send sends the entries, and retries the items rejected by 429 or 5xx with backoff. The other
+// failing items, and the entries missing from the items of the response, are dropped.
+func (s *elasticsearchSender) send(entries [][]byte) (int, error) {
+	var (
+		n       = len(entries)
+		dropped int
+		lastErr error
+	)
+	for retry := 0; len(entries) > 0; retry++ {
+		body, err := s.client.post(bytes.Join(entries, nil), "application/x-ndjson")
+		if err != nil {
+			dropped, lastErr = dropped+len(entries), err
+			break
+		}
+		resp := &elasticsearchBulkResponse{}
+		if err := json.Unmarshal(body, resp); err != nil {
+			dropped, lastErr = dropped+len(entries), fmt.Errorf("bulk response invalid: %v", err)
+			break
+		}
+		if !resp.Errors && len(resp.Items) >= len(entries) {
+			break
+		}
+		if len(resp.Items) < len(entries) {
+			// The entries without items may or may not be indexed, so they are not sent again.
+			dropped += len(entries) - len(resp.Items)
+			lastErr = fmt.Errorf("bulk response has %d items for %d entries", len(resp.Items), len(entries))
+		}
+		var again [][]byte
+		for i, item := range resp.Items {
+			if i >= len(entries) {
+				break
+			}
+			for _, result := range item {
+				switch {
+				case result.Status >= 200 && result.Status <= 299:
+				case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+					again = append(again, entries[i])
+				default:
+					dropped++
+					lastErr = fmt.Errorf("bulk item status %d: %s", result.Status, result.Error)
+				}
+			}
+		}
+		if len(again) > 0 && retry >= s.client.cfg.MaxRetries {
+			dropped += len(again)
+			lastErr = fmt.Errorf("bulk items rejected after %d retries", retry)
+			break
+		}
+		if len(again) > 0 && !s.client.wait(s.client.backoff(retry)) {
+			dropped += len(again)
+			lastErr = errors.New("bulk items rejected before the writer is closed")
+			break
+		}
+		entries = again
+	}
+	if dropped > 0 {
+		return 0, &discardError{err: lastErr, n: dropped}
+	}
+	return n, nil
+}
@@ -0,0 +1,200 @@
+package log_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newElasticsearchLogger creates a Logger with an elasticsearch output configured by
+// remote_config.
+func newElasticsearchLogger(t *testing.T, remote string) log.Logger {
+	return newWriterLogger(t, "{writer: elasticsearch, formatter_config: {time_zone: UTC}}", remote)
+}
+
+// fakeBulk is a fake bulk API, which responds the status of items by their messages in order.
+type fakeBulk struct {
+	mu       sync.Mutex
+	path     string
+	auth     string
+	statuses map[string][]int // message => statuses of its attempts, 201 after them
+	indexed  map[string][]string
+	requests int
+	maxItems int // max number of items responded if it is not 0
+}
+
+func (f *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.path, f.auth = r.URL.Path, r.Header.Get("Authorization")
+	f.requests++
+
+	type result struct {
+		Status int         `json:"status"`
+		Error  interface{} `json:"error,omitempty"`
+	}
+	var (
+		items  []map[string]result
+		errors bool
+	)
+	s := bufio.NewScanner(r.Body)
+	for s.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(s.Bytes(), &action); err != nil || !s.Scan() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(s.Bytes(), &doc); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		msg := doc["M"].(string)
+		res := result{Status: http.StatusCreated}
+		if statuses := f.statuses[msg]; len(statuses) > 0 {
+			res.Status, f.statuses[msg] = statuses[0], statuses[1:]
+			res.Error = map[string]string{"type": "rejected"}
+			errors = true
+		} else {
+			index := action["create"]["_index"]
+			f.indexed[index] = append(f.indexed[index], msg)
+		}
+		items = append(items, map[string]result{"create": res})
+	}
+	if f.maxItems > 0 && len(items) > f.maxItems {
+		items = items[:f.maxItems]
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": errors, "items": items})
+}
+
+func TestElasticsearchWriter(t *testing.T) {
+	bulk := &fakeBulk{
+		statuses: map[string][]int{"busy": {429, 503}, "invalid": {400}},
+		indexed:  make(map[string][]string),
+	}
+	srv := httptest.NewServer(bulk)
+	defer srv.Close()
+
+	_, stderr := redirectStd(t, func() {
+		logger := newElasticsearchLogger(t, fmt.Sprintf("{url: %s/es/, index: 'app-%%Y.%%m.%%d', "+
+			"username: elastic, password: secret, min_backoff: 1, write_log_interval: 60000}", srv.URL))
+		logger.Info("first")
+		logger.Info("busy")
+		logger.Info("invalid")
+		logger.With(log.Field{Key: "uid", Value: 1}).Info("last")
+		assert.NotNil(t, logger.Sync())
+	})
+
+	bulk.mu.Lock()
+	defer bulk.mu.Unlock()
+	assert.Equal(t, "/es/_bulk", bulk.path)
+	assert.Equal(t, "Basic ZWxhc3RpYzpzZWNyZXQ=", bulk.auth)
+	assert.Equal(t, 3, bulk.requests)
+	index := "app-" + time.Now().UTC().Format("2006.01.02")
+	assert.Equal(t, map[string][]string{index: {"first", "last", "busy"}}, bulk.indexed)
+	assert.Contains(t, stderr, "log: elasticsearch writer "+srv.URL+"/es/_bulk dropped 1 entries\n")
+}
+
+func TestElasticsearchWriterRetryExhausted(t *testing.T) {
+	bulk := &fakeBulk{
+		statuses: map[string][]int{"busy": {503, 503, 503}},
+		indexed:  make(map[string][]string),
+	}
+	srv := httptest.NewServer(bulk)
+	defer srv.Close()
+
+	_, stderr := redirectStd(t, func() {
+		logger := newElasticsearchLogger(t, fmt.Sprintf(
+			"{url: %s, max_retries: 2, min_backoff: 1, write_log_interval: 60000}", srv.URL))
+		logger.Info("busy")
+		logger.Info("ok")
+		assert.NotNil(t, logger.Sync())
+	})
+
+	bulk.mu.Lock()
+	defer bulk.mu.Unlock()
+	assert.Equal(t, 3, bulk.requests)
+	index := "logs-" + time.Now().UTC().Format("2006.01.02")
+	assert.Equal(t, map[string][]string{index: {"ok"}}, bulk.indexed)
+	assert.Contains(t, stderr, "dropped 1 entries\n")
+}
+
+func TestElasticsearchWriterBulkURL(t *testing.T) {
+	for _, path := range []string{"/_bulk", "/logs/_bulk/"} {
+		bulk := &fakeBulk{indexed: make(map[string][]string)}
+		srv := httptest.NewServer(bulk)
+
+		logger := newElasticsearchLogger(t, fmt.Sprintf("{url: %s%s, write_log_interval: 60000}", srv.URL, path))
+		logger.Info("first")
+		assert.Nil(t, logger.Sync())
+		bulk.mu.Lock()
+		assert.Equal(t, path, bulk.path)
+		bulk.mu.Unlock()
+		srv.Close()
+	}
+}
+
+func TestElasticsearchWriterMissingItems(t *testing.T) {
+	bulk := &fakeBulk{indexed: make(map[string][]string), maxItems: 1}
+	srv := httptest.NewServer(bulk)
+	defer srv.Close()
+
+	_, stderr := redirectStd(t, func() {
+		logger := newElasticsearchLogger(t, fmt.Sprintf(
+			"{url: %s, min_backoff: 1, write_log_interval: 60000}", srv.URL))
+		logger.Info("first")
+		logger.Info("second")
+		assert.EqualError(t, logger.Sync(), "bulk response has 1 items for 2 entries")
+	})
+
+	bulk.mu.Lock()
+	defer bulk.mu.Unlock()
+	assert.Equal(t, 1, bulk.requests)
+	assert.Contains(t, stderr, "dropped 1 entries\n")
+}
+
+func TestElasticsearchWriterCloseDuringBackoff(t *testing.T) {
+	bulk := &fakeBulk{
+		statuses: map[string][]int{"busy": {503, 503, 503}},
+		indexed:  make(map[string][]string),
+	}
+	srv := httptest.NewServer(bulk)
+	defer srv.Close()
+
+	_, stderr := redirectStd(t, func() {
+		logger := newElasticsearchLogger(t, fmt.Sprintf(
+			"{url: %s, min_backoff: 10000, write_log_interval: 10}", srv.URL))
+		logger.Info("busy")
+		assert.Eventually(t, func() bool {
+			bulk.mu.Lock()
+			defer bulk.mu.Unlock()
+			return bulk.requests > 0
+		}, time.Second, 5*time.Millisecond)
+
+		start := time.Now()
+		assert.Nil(t, log.CloseOutputs(logger))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+	assert.Contains(t, stderr, "dropped 1 entries\n")
+}
+
+func TestElasticsearchWriterInvalidConfig(t *testing.T) {
+	for _, remote := range []string{
+		"{}",
+		"{url: 'http://127.0.0.1:9200', index: 'logs-%Q'}",
+	} {
+		assert.Panics(t, func() { newElasticsearchLogger(t, remote) }, remote)
+	}
+	assert.Panics(t, func() {
+		log.NewZapLog([]log.OutputConfig{{Writer: "elasticsearch", Formatter: "console"}})
+	})
+}
@@ -0,0 +1,14 @@
+package log
+
+// CloseLogger syncs and closes the outputs of a Logger created by NewZapLog, so that tests can
+// release the connections and goroutines of its writers.
+func CloseLogger(l Logger) error {
+	cores := toZapLog(l).cores.Load().(*zapCores)
+	_ = cores.core.Sync()
+	return cores.close()
+}
+
+// CloseOutputs closes the outputs of a Logger created by NewZapLog without syncing them first.
+func CloseOutputs(l Logger) error {
+	return toZapLog(l).cores.Load().(*zapCores).close()
+}
@@ -0,0 +1,458 @@
+package log
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// encodedField is a field collected by fieldEncoder.
+// The value is one of bool, int64, uint64, float64, complex128, string, nil, []encodedField for
+// objects, []interface{} for arrays, or reflectedValue.
+type encodedField struct {
+	key   string
+	value interface{}
+}
+
+// reflectedValue is a value added by AddReflected other than maps and structs, which is rendered
+// as json.
+type reflectedValue struct {
+	v interface{}
+}
+
+// fieldEncoder is a zapcore.ObjectEncoder which keeps fields in the order they are added, so that
+// text formatters can render all or a subset of them deterministically. Fields in a namespace are
+// collected with dotted keys like "namespace.key".
+type fieldEncoder struct {
+	cfg    *zapcore.EncoderConfig
+	fields []encodedField
+	prefix string
+}
+
+func newFieldEncoder(cfg *zapcore.EncoderConfig) *fieldEncoder {
+	return &fieldEncoder{cfg: cfg}
+}
+
+// clone returns a copy of the encoder which does not share fields with it.
+func (e *fieldEncoder) clone() *fieldEncoder {
+	fields := make([]encodedField, len(e.fields), len(e.fields)+8)
+	copy(fields, e.fields)
+	return &fieldEncoder{cfg: e.cfg, fields: fields, prefix: e.prefix}
+}
+
+func (e *fieldEncoder) add(key string, value interface{}) {
+	e.fields = append(e.fields, encodedField{key: e.prefix + key, value: value})
+}
+
+// AddArray implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &arrayEncoder{cfg: e.cfg}
+	err := marshaler.MarshalLogArray(arr)
+	e.add(key, arr.elems)
+	return err
+}
+
+// AddObject implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	obj := newFieldEncoder(e.cfg)
+	err := marshaler.MarshalLogObject(obj)
+	e.add(key, obj.fields)
+	return err
+}
+
+// AddBinary implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddBinary(key string, value []byte) {
+	e.add(key, base64.StdEncoding.EncodeToString(value))
+}
+
+// AddByteString implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddByteString(key string, value []byte) { e.add(key, string(value)) }
+
+// AddBool implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddBool(key string, value bool) { e.add(key, value) }
+
+// AddComplex128 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddComplex128(key string, value complex128) { e.add(key, value) }
+
+// AddComplex64 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddComplex64(key string, value complex64) { e.add(key, complex128(value)) }
+
+// AddDuration implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddDuration(key string, value time.Duration) {
+	e.add(key, encodeDuration(e.cfg, value))
+}
+
+// AddFloat64 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddFloat64(key string, value float64) { e.add(key, value) }
+
+// AddFloat32 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddFloat32(key string, value float32) { e.add(key, float64(value)) }
+
+// AddInt implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddInt(key string, value int) { e.add(key, int64(value)) }
+
+// AddInt64 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddInt64(key string, value int64) { e.add(key, value) }
+
+// AddInt32 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddInt32(key string, value int32) { e.add(key, int64(value)) }
+
+// AddInt16 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddInt16(key string, value int16) { e.add(key, int64(value)) }
+
+// AddInt8 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddInt8(key string, value int8) { e.add(key, int64(value)) }
+
+// AddString implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddString(key, value string) { e.add(key, value) }
+
+// AddTime implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddTime(key string, value time.Time) {
+	e.add(key, encodeTime(e.cfg, value))
+}
+
+// AddUint implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddUint(key string, value uint) { e.add(key, uint64(value)) }
+
+// AddUint64 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddUint64(key string, value uint64) { e.add(key, value) }
+
+// AddUint32 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddUint32(key string, value uint32) { e.add(key, uint64(value)) }
+
+// AddUint16 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddUint16(key string, value uint16) { e.add(key, uint64(value)) }
+
+// AddUint8 implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddUint8(key string, value uint8) { e.add(key, uint64(value)) }
+
+// AddUintptr implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) AddUintptr(key string, value uintptr) { e.add(key, uint64(value)) }
+
+// AddReflected implements zapcore.ObjectEncoder. Maps and structs are collected as objects, so
+// that their fields are flattened into dotted keys like the fields of ObjectMarshalers.
+func (e *fieldEncoder) AddReflected(key string, value interface{}) error {
+	if obj, ok := reflectedObject(value); ok {
+		e.add(key, obj)
+		return nil
+	}
+	e.add(key, reflectedValue{v: value})
+	return nil
+}
+
+// OpenNamespace implements zapcore.ObjectEncoder.
+func (e *fieldEncoder) OpenNamespace(key string) {
+	e.prefix = e.prefix + key + "."
+}
+
+// arrayEncoder is a zapcore.ArrayEncoder which collects the elements of an array.
+type arrayEncoder struct {
+	cfg   *zapcore.EncoderConfig
+	elems []interface{}
+}
+
+func (a *arrayEncoder) append(v interface{}) { a.elems = append(a.elems, v) }
+
+// AppendArray implements zapcore.ArrayEncoder.
+func (a *arrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	arr := &arrayEncoder{cfg: a.cfg}
+	err := marshaler.MarshalLogArray(arr)
+	a.append(arr.elems)
+	return err
+}
+
+// AppendObject implements zapcore.ArrayEncoder.
+func (a *arrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	obj := newFieldEncoder(a.cfg)
+	err := marshaler.MarshalLogObject(obj)
+	a.append(obj.fields)
+	return err
+}
+
+// AppendReflected implements zapcore.ArrayEncoder.
+func (a *arrayEncoder) AppendReflected(value interface{}) error {
+	if obj, ok := reflectedObject(value); ok {
+		a.append(obj)
+		return nil
+	}
+	a.append(reflectedValue{v: value})
+	return nil
+}
+
+// AppendBool implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendBool(v bool) { a.append(v) }
+
+// AppendByteString implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendByteString(v []byte) { a.append(string(v)) }
+
+// AppendComplex128 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendComplex128(v complex128) { a.append(v) }
+
+// AppendComplex64 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendComplex64(v complex64) { a.append(complex128(v)) }
+
+// AppendDuration implements zapcore.ArrayEncoder.
+func (a *arrayEncoder) AppendDuration(v time.Duration) { a.append(encodeDuration(a.cfg, v)) }
+
+// AppendFloat64 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendFloat64(v float64) { a.append(v) }
+
+// AppendFloat32 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendFloat32(v float32) { a.append(float64(v)) }
+
+// AppendInt implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendInt(v int) { a.append(int64(v)) }
+
+// AppendInt64 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendInt64(v int64) { a.append(v) }
+
+// AppendInt32 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendInt32(v int32) { a.append(int64(v)) }
+
+// AppendInt16 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendInt16(v int16) { a.append(int64(v)) }
+
+// AppendInt8 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendInt8(v int8) { a.append(int64(v)) }
+
+// AppendString implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendString(v string) { a.append(v) }
+
+// AppendTime implements zapcore.ArrayEncoder.
+func (a *arrayEncoder) AppendTime(v time.Time) { a.append(encodeTime(a.cfg, v)) }
+
+// AppendUint implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendUint(v uint) { a.append(uint64(v)) }
+
+// AppendUint64 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendUint64(v uint64) { a.append(v) }
+
+// AppendUint32 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendUint32(v uint32) { a.append(uint64(v)) }
+
+// AppendUint16 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendUint16(v uint16) { a.append(uint64(v)) }
+
+// AppendUint8 implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendUint8(v uint8) { a.append(uint64(v)) }
+
+// AppendUintptr implements zapcore.PrimitiveArrayEncoder.
+func (a *arrayEncoder) AppendUintptr(v uintptr) { a.append(uint64(v)) }
+
+// single returns the only element appended by an encoder function like zapcore.TimeEncoder, or all
+// of them joined by space.
+func (a *arrayEncoder) single() interface{} {
+	if len(a.elems) == 1 {
+		return a.elems[0]
+	}
+	parts := make([]string, len(a.elems))
+	for i, v := range a.elems {
+		parts[i] = textValue(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// reflectedObject collects a map or a struct by its json encoding, which keeps the order of
+// struct fields and sorts map keys. Values encoded as other than json objects, like time.Time, are
+// not collected.
+func reflectedObject(value interface{}) ([]encodedField, bool) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Map && v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	data, err := json.Marshal(value)
+	if err != nil || len(data) == 0 || data[0] != '{' {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	obj, err := decodeJSONValue(dec)
+	fields, ok := obj.([]encodedField)
+	return fields, err == nil && ok
+}
+
+// decodeJSONValue decodes the next json value into a collected value, with objects in order.
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			fields := []encodedField{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, encodedField{key: key.(string), value: value})
+			}
+			_, err = dec.Token()
+			return fields, err
+		}
+		elems := []interface{}{}
+		for dec.More() {
+			elem, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		_, err = dec.Token()
+		return elems, err
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return i, nil
+		}
+		return tok.Float64()
+	default:
+		// string, bool or nil.
+		return tok, nil
+	}
+}
+
+// encodeTime encodes t by the configured time encoder.
+func encodeTime(cfg *zapcore.EncoderConfig, t time.Time) interface{} {
+	if cfg == nil || cfg.EncodeTime == nil {
+		return t.Format(time.RFC3339Nano)
+	}
+	arr := &arrayEncoder{cfg: cfg}
+	cfg.EncodeTime(t, arr)
+	return arr.single()
+}
+
+// encodeDuration encodes d by the configured duration encoder.
+func encodeDuration(cfg *zapcore.EncoderConfig, d time.Duration) interface{} {
+	if cfg == nil || cfg.EncodeDuration == nil {
+		return d.String()
+	}
+	arr := &arrayEncoder{cfg: cfg}
+	cfg.EncodeDuration(d, arr)
+	return arr.single()
+}
+
+// encodeLevel encodes l by the configured level encoder.
+func encodeLevel(cfg *zapcore.EncoderConfig, l zapcore.Level) string {
+	if cfg == nil || cfg.EncodeLevel == nil {
+		return l.CapitalString()
+	}
+	arr := &arrayEncoder{cfg: cfg}
+	cfg.EncodeLevel(l, arr)
+	return textValue(arr.single())
+}
+
+// encodeCaller encodes caller by the configured caller encoder.
+func encodeCaller(cfg *zapcore.EncoderConfig, caller zapcore.EntryCaller) string {
+	if !caller.Defined {
+		return ""
+	}
+	if cfg == nil || cfg.EncodeCaller == nil {
+		return caller.TrimmedPath()
+	}
+	arr := &arrayEncoder{cfg: cfg}
+	cfg.EncodeCaller(caller, arr)
+	return textValue(arr.single())
+}
+
+// flattenFields calls fn with each field. Fields of nested objects are flattened into dotted keys.
+func flattenFields(prefix string, fields []encodedField, fn func(key string, value interface{})) {
+	for _, f := range fields {
+		// empty objects are rendered as {}, so that their keys are kept.
+		if obj, ok := f.value.([]encodedField); ok && len(obj) > 0 {
+			flattenFields(prefix+f.key+".", obj, fn)
+			continue
+		}
+		fn(prefix+f.key, f.value)
+	}
+}
+
+// textValue renders a collected value as text.
+func textValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return formatFloat(v)
+	case complex128:
+		return formatFloat(real(v)) + "+" + formatFloat(imag(v)) + "i"
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, elem := range v {
+			parts[i] = quoteIfNeeded(textValue(elem))
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case []encodedField:
+		parts := make([]string, 0, len(v))
+		flattenFields("", v, func(key string, value interface{}) {
+			parts = append(parts, key+"="+quoteIfNeeded(textValue(value)))
+		})
+		return "{" + strings.Join(parts, " ") + "}"
+	case reflectedValue:
+		if b, err := json.Marshal(v.v); err == nil {
+			return string(b)
+		}
+		return fmt.Sprint(v.v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+}
+
+// needsQuote reports whether s must be quoted to be parsed back as a single key=value token.
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteIfNeeded quotes s with Go escaping if it can not be a single key=value token.
+func quoteIfNeeded(s string) string {
+	if needsQuote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
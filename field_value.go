@@ -0,0 +1,26 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// fieldValueString formats a value collected by zapcore.MapObjectEncoder as a string. It is shared
+// by the values of journal fields, and by the comparisons of memory queries and route rules.
+func fieldValueString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case []interface{}, map[string]interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(v)
+}
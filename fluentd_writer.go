@@ -0,0 +1,234 @@
+package log
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// modes of the fluentd writer.
+const (
+	FluentdForward       = "forward"
+	FluentdPackedForward = "packed_forward"
+)
+
+// FluentdConfig is the config of the fluentd writer, which is set in remote_config.
+type FluentdConfig struct {
+	// Network is tcp, or its variants like tcp4, default as tcp.
+	Network string `yaml:"network"`
+	// Address is the address of the forward input, like "127.0.0.1:24224".
+	Address string `yaml:"address"`
+	// Tag is the tag of events, default as tlog.
+	Tag string `yaml:"tag"`
+	// Mode is the message mode of the forward protocol, forward or packed_forward, default as
+	// forward.
+	Mode string `yaml:"mode"`
+	// RequireAck defines whether each message waits for the ack of its chunk. Messages which are
+	// not acked in Timeout are sent again on a new connection.
+	RequireAck bool `yaml:"require_ack"`
+	// Timeout is the timeout in milliseconds to connect, to write and to wait for acks, default as
+	// 5000.
+	Timeout int `yaml:"timeout"`
+	// MinBackoff and MaxBackoff are the bounds in milliseconds of the exponential backoff between
+	// reconnections, default as 100 and 30000.
+	MinBackoff int `yaml:"min_backoff"`
+	MaxBackoff int `yaml:"max_backoff"`
+
+	BatchConfig `yaml:",inline"`
+}
+
+// FluentdWriterFactory is the fluentd writer instance Factory.
+type FluentdWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *FluentdWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers fluentd output writer. Entries encoded by the json formatter,
+// which is the default, are sent as records of their fields, and entries encoded by the other
+// formatters are sent as records with a single message field.
+func (f *FluentdWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("fluentd writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("fluentd writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	fc := &FluentdConfig{}
+	if err := decodeRemoteConfig(cfg, fc); err != nil {
+		return fmt.Errorf("fluentd writer remote config invalid: %v", err)
+	}
+	mode := GetLogEncoderKey(FluentdForward, fc.Mode)
+	if mode != FluentdForward && mode != FluentdPackedForward {
+		return fmt.Errorf("fluentd writer mode %s invalid", fc.Mode)
+	}
+	network := GetLogEncoderKey("tcp", fc.Network)
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return fmt.Errorf("fluentd writer network %s invalid", fc.Network)
+	}
+	conn, err := newNetConn(&NetConfig{
+		Network:    network,
+		Address:    fc.Address,
+		Timeout:    fc.Timeout,
+		MinBackoff: fc.MinBackoff,
+		MaxBackoff: fc.MaxBackoff,
+	})
+	if err != nil {
+		return fmt.Errorf("fluentd writer %v", err)
+	}
+	s := &fluentdSender{
+		conn:   conn,
+		tag:    GetLogEncoderKey("tlog", fc.Tag),
+		packed: mode == FluentdPackedForward,
+		ack:    fc.RequireAck,
+	}
+	w := newBatchWriter("fluentd writer "+fc.Address, fc.BatchConfig, s.send)
+
+	cfg.Formatter = GetLogEncoderKey(FormatterJSON, cfg.Formatter)
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core = &fluentdCore{
+		LevelEnabler: lvl,
+		enc:          newEncoder(cfg),
+		w:            w,
+		json:         cfg.Formatter == FormatterJSON,
+	}
+	decoder.ZapLevel = lvl
+	decoder.Closer = closerFunc(func() error {
+		err := w.Close()
+		conn.close()
+		return err
+	})
+	return nil
+}
+
+// fluentdCore is a zapcore.Core which queues each entry as a MessagePack encoded [time, record]
+// event.
+type fluentdCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	w    *batchWriter
+	json bool // whether enc encodes entries as JSON objects
+}
+
+// With implements zapcore.Core.
+func (c *fluentdCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *fluentdCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *fluentdCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	line := bytes.TrimRight(buf.Bytes(), "\r\n")
+
+	var record interface{}
+	if c.json {
+		d := json.NewDecoder(bytes.NewReader(line))
+		d.UseNumber()
+		if err := d.Decode(&record); err != nil {
+			record = nil
+		}
+	}
+	if _, ok := record.(map[string]interface{}); !ok {
+		record = map[string]interface{}{"message": string(line)}
+	}
+	event := appendMsgpackArrayHeader(make([]byte, 0, len(line)+16), 2)
+	event = appendMsgpackEventTime(event, ent.Time)
+	event = appendMsgpack(event, record)
+	_, err = c.w.Write(event)
+	return err
+}
+
+// Sync implements zapcore.Core.
+func (c *fluentdCore) Sync() error {
+	return c.w.Sync()
+}
+
+// fluentdSender sends batches of events queued by fluentdCore as forward protocol messages.
+type fluentdSender struct {
+	conn   *netConn
+	tag    string
+	packed bool
+	ack    bool
+}
+
+func (s *fluentdSender) send(events [][]byte) (int, error) {
+	option := map[string]interface{}{"size": int64(len(events))}
+	var chunk string
+	if s.ack {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return 0, err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = chunk
+	}
+	msg := appendMsgpackArrayHeader(nil, 3)
+	msg = appendMsgpackString(msg, s.tag)
+	if s.packed {
+		msg = appendMsgpackBin(msg, bytes.Join(events, nil))
+	} else {
+		msg = appendMsgpackArrayHeader(msg, len(events))
+		for _, event := range events {
+			msg = append(msg, event...)
+		}
+	}
+	msg = appendMsgpack(msg, option)
+
+	c := s.conn
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return 0, err
+		}
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(msg); err != nil {
+		c.fail()
+		return 0, err
+	}
+	if !s.ack {
+		return len(events), nil
+	}
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	resp, err := readMsgpackStringMap(c.conn)
+	if err == nil && resp["ack"] != chunk {
+		err = fmt.Errorf("ack %q not match chunk %q", resp["ack"], chunk)
+	}
+	if err != nil {
+		// The events are sent again on a new connection, which may duplicate them.
+		c.fail()
+		return 0, err
+	}
+	return len(events), nil
+}
@@ -0,0 +1,222 @@
+package log_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFluentdLogger creates a Logger with a fluentd output configured by remote_config.
+func newFluentdLogger(t *testing.T, formatter, remote string) log.Logger {
+	return newWriterLogger(t, `{writer: fluentd, formatter: `+formatter+
+		`, formatter_config: {pattern: "%msg %fields"}}`, remote)
+}
+
+// fluentdEvent is an event decoded from a forward message.
+type fluentdEvent struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// readFluentdMessage reads a forward or packed forward message.
+func readFluentdMessage(t *testing.T, r *bufio.Reader) (string, []fluentdEvent, map[string]interface{}) {
+	msg, err := decodeMsgpack(r)
+	assert.Nil(t, err)
+	arr := msg.([]interface{})
+	assert.Len(t, arr, 3)
+
+	var raw []interface{}
+	switch entries := arr[1].(type) {
+	case []interface{}:
+		raw = entries
+	case []byte:
+		pr := bufio.NewReader(bytes.NewReader(entries))
+		for {
+			e, err := decodeMsgpack(pr)
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(t, err)
+			raw = append(raw, e)
+		}
+	}
+	var events []fluentdEvent
+	for _, e := range raw {
+		pair := e.([]interface{})
+		events = append(events, fluentdEvent{
+			time:   pair[0].(time.Time),
+			record: pair[1].(map[string]interface{}),
+		})
+	}
+	return arr[0].(string), events, arr[2].(map[string]interface{})
+}
+
+// decodeMsgpack decodes the MessagePack types written by the fluentd writer.
+func decodeMsgpack(r *bufio.Reader) (interface{}, error) {
+	head, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		_, _ = io.ReadFull(r, b)
+		return b
+	}
+	size := func(n int) int {
+		b := read(n)
+		v := 0
+		for _, c := range b {
+			v = v<<8 | int(c)
+		}
+		return v
+	}
+	array := func(n int) (interface{}, error) {
+		arr := make([]interface{}, n)
+		for i := range arr {
+			if arr[i], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	object := func(n int) (interface{}, error) {
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[k.(string)], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+	switch {
+	case head <= 0x7f:
+		return int64(head), nil
+	case head >= 0xe0:
+		return int64(int8(head)), nil
+	case head&0xf0 == 0x80:
+		return object(int(head & 0x0f))
+	case head&0xf0 == 0x90:
+		return array(int(head & 0x0f))
+	case head&0xe0 == 0xa0:
+		return string(read(int(head & 0x1f))), nil
+	}
+	switch head {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return head == 0xc3, nil
+	case 0xc4, 0xc5, 0xc6:
+		return read(size(1 << (head - 0xc4))), nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(read(8))), nil
+	case 0xd0:
+		return int64(int8(read(1)[0])), nil
+	case 0xd1:
+		return int64(int16(binary.BigEndian.Uint16(read(2)))), nil
+	case 0xd2:
+		return int64(int32(binary.BigEndian.Uint32(read(4)))), nil
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(read(8))), nil
+	case 0xd7:
+		b := read(9)
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:])), int64(binary.BigEndian.Uint32(b[5:]))), nil
+	case 0xd9, 0xda, 0xdb:
+		return string(read(size(1 << (head - 0xd9)))), nil
+	case 0xdc, 0xdd:
+		return array(size(2 << (head - 0xdc)))
+	case 0xde, 0xdf:
+		return object(size(2 << (head - 0xde)))
+	}
+	return nil, fmt.Errorf("msgpack type 0x%x not expected", head)
+}
+
+func TestFluentdWriterForward(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	logger := newFluentdLogger(t, "json", fmt.Sprintf(
+		"{address: %s, tag: app.access, write_log_interval: 60000}", ln.Addr()))
+	logger.With(log.Field{Key: "uid", Value: 1}).Info("hello")
+	logger.Errorw("oops", "cost", 1.5, "ok", false)
+	now := time.Now()
+	assert.Nil(t, logger.Sync())
+
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+	tag, events, option := readFluentdMessage(t, bufio.NewReader(conn))
+	assert.Equal(t, "app.access", tag)
+	assert.Equal(t, map[string]interface{}{"size": int64(2)}, option)
+	assert.Len(t, events, 2)
+	assert.WithinDuration(t, now, events[0].time, time.Second)
+	assert.Equal(t, "hello", events[0].record["M"])
+	assert.Equal(t, "INFO", events[0].record["L"])
+	assert.Equal(t, int64(1), events[0].record["uid"])
+	assert.Equal(t, "oops", events[1].record["M"])
+	assert.Equal(t, 1.5, events[1].record["cost"])
+	assert.Equal(t, false, events[1].record["ok"])
+}
+
+func TestFluentdWriterPackedForwardAck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	received := make(chan []fluentdEvent, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, events, option := readFluentdMessage(t, bufio.NewReader(conn))
+			received <- events
+			if i == 0 {
+				// drop the connection without ack, so that the message is sent again.
+				conn.Close()
+				continue
+			}
+			chunk := option["chunk"].(string)
+			ack := append([]byte{0x81, 0xa3, 'a', 'c', 'k', 0xa0 | byte(len(chunk))}, chunk...)
+			_, _ = conn.Write(ack)
+			defer conn.Close()
+		}
+	}()
+
+	logger := newFluentdLogger(t, "pattern", fmt.Sprintf("{address: %s, mode: packed_forward, "+
+		"require_ack: true, timeout: 1000, min_backoff: 1, write_log_interval: 60000}", ln.Addr()))
+	logger.Info("hello")
+	assert.NotNil(t, logger.Sync())
+	assert.Eventually(t, func() bool { return logger.Sync() == nil }, time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		events := <-received
+		assert.Len(t, events, 1)
+		assert.Equal(t, map[string]interface{}{"message": "hello"}, events[0].record)
+	}
+}
+
+func TestFluentdWriterInvalidConfig(t *testing.T) {
+	for _, remote := range []string{
+		"{}",
+		"{address: 127.0.0.1:24224, mode: message}",
+		"{address: 127.0.0.1:24224, network: udp}",
+	} {
+		assert.Panics(t, func() { newFluentdLogger(t, "json", remote) }, remote)
+	}
+}
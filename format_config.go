@@ -0,0 +1,92 @@
+package log
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Some level encoder names of FormatConfig.LevelEncoder.
+const (
+	LevelEncoderCapital        = "capital"
+	LevelEncoderLowercase      = "lowercase"
+	LevelEncoderColor          = "color"
+	LevelEncoderLowercaseColor = "lowercase_color"
+)
+
+var levelEncoders = map[string]zapcore.LevelEncoder{
+	"":                         CapitalLevelEncoder,
+	LevelEncoderCapital:        CapitalLevelEncoder,
+	LevelEncoderLowercase:      LowercaseLevelEncoder,
+	LevelEncoderColor:          CapitalColorLevelEncoder,
+	LevelEncoderLowercaseColor: LowercaseColorLevelEncoder,
+}
+
+var callerEncoders = map[string]zapcore.CallerEncoder{
+	"":      zapcore.ShortCallerEncoder,
+	"short": zapcore.ShortCallerEncoder,
+	"full":  zapcore.FullCallerEncoder,
+}
+
+var durationEncoders = map[string]zapcore.DurationEncoder{
+	"":        zapcore.StringDurationEncoder,
+	"string":  zapcore.StringDurationEncoder,
+	"seconds": zapcore.SecondsDurationEncoder,
+	"millis":  zapcore.MillisDurationEncoder,
+	"nanos":   zapcore.NanosDurationEncoder,
+}
+
+// validateFormatConfig checks the encoder names and the time zone of the format config.
+func validateFormatConfig(c *FormatConfig) error {
+	if _, ok := levelEncoders[c.LevelEncoder]; !ok {
+		return fmt.Errorf("level encoder %s not exist", c.LevelEncoder)
+	}
+	if _, ok := callerEncoders[c.CallerEncoder]; !ok {
+		return fmt.Errorf("caller encoder %s not exist", c.CallerEncoder)
+	}
+	if _, ok := durationEncoders[c.DurationEncoder]; !ok {
+		return fmt.Errorf("duration encoder %s not exist", c.DurationEncoder)
+	}
+	switch c.Color {
+	case "", ColorAuto, ColorAlways, ColorNever:
+	default:
+		return fmt.Errorf("color %s invalid, one of auto/always/never expected", c.Color)
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			return fmt.Errorf("time zone %s invalid: %v", c.TimeZone, err)
+		}
+	}
+	return nil
+}
+
+// levelEncoder returns the level encoder of the output. Levels are colored if the level encoder is
+// a color one or colors are enabled by FormatConfig.Color.
+func levelEncoder(c *OutputConfig) zapcore.LevelEncoder {
+	name := c.FormatConfig.LevelEncoder
+	if colorEnabled(c) {
+		switch name {
+		case "", LevelEncoderCapital:
+			name = LevelEncoderColor
+		case LevelEncoderLowercase:
+			name = LevelEncoderLowercaseColor
+		}
+	}
+	if encode, ok := levelEncoders[name]; ok {
+		return encode
+	}
+	return CapitalLevelEncoder
+}
+
+// timeZone returns the time zone of the output, the local one if it is not configured or invalid.
+func timeZone(c *OutputConfig) *time.Location {
+	if c.FormatConfig.TimeZone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.FormatConfig.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
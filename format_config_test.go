@@ -0,0 +1,90 @@
+package log_test
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFormatConfigEncoders(t *testing.T) {
+	ent := newTestEntry()
+	ent.Time = time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	encode := func(fc log.FormatConfig) string {
+		cfg := log.NewEncoderConfig(&log.OutputConfig{Writer: "file", FormatConfig: fc})
+		cfg.NameKey, cfg.MessageKey = "", ""
+		buf, err := zapcore.NewJSONEncoder(cfg).EncodeEntry(ent, []zapcore.Field{
+			zap.Duration("cost", 1500*time.Millisecond),
+		})
+		assert.Nil(t, err)
+		return buf.String()
+	}
+
+	assert.Equal(t, `{"L":"INFO","T":"2006-01-02 15:04:05.000","C":"tlog/server.go:42","cost":"1.5s"}`+"\n",
+		encode(log.FormatConfig{TimeZone: "UTC"}))
+	assert.Equal(t, `{"L":"info","T":"2006-01-02 23:04:05.000","C":"/src/tlog/server.go:42","cost":1.5}`+"\n",
+		encode(log.FormatConfig{
+			LevelEncoder:    log.LevelEncoderLowercase,
+			CallerEncoder:   "full",
+			DurationEncoder: "seconds",
+			TimeZone:        "Asia/Shanghai",
+		}))
+	assert.Equal(t, `{"L":"\u001b[34minfo\u001b[0m","T":"15:04 UTC","C":"tlog/server.go:42","cost":1500000000}`+"\n",
+		encode(log.FormatConfig{
+			LevelEncoder:    log.LevelEncoderLowercaseColor,
+			DurationEncoder: "nanos",
+			TimeFmt:         "15:04 MST",
+			TimeZone:        "UTC",
+		}))
+	assert.Contains(t, encode(log.FormatConfig{LevelEncoder: log.LevelEncoderColor, DurationEncoder: "millis"}),
+		`"L":"\u001b[34mINFO\u001b[0m"`)
+}
+
+func TestLowercaseLevelEncoder(t *testing.T) {
+	cfg := zapcore.EncoderConfig{LevelKey: "L", EncodeLevel: log.LowercaseLevelEncoder}
+	buf, err := zapcore.NewConsoleEncoder(cfg).EncodeEntry(zapcore.Entry{Level: log.ZapTraceLevel}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "trace\n", buf.String())
+}
+
+func TestPatternTimeZone(t *testing.T) {
+	stdout, _ := redirectStd(t, func() {
+		logger := log.NewZapLog([]log.OutputConfig{{
+			Writer:    "console",
+			Formatter: "pattern",
+			FormatConfig: log.FormatConfig{
+				Pattern:  "%time{MST} %time %msg",
+				TimeFmt:  "Z07:00",
+				TimeZone: "UTC",
+			},
+		}})
+		logger.Info("utc")
+	})
+	assert.Equal(t, "UTC Z utc\n", stdout)
+}
+
+func TestInvalidFormatConfig(t *testing.T) {
+	for _, fc := range []log.FormatConfig{
+		{LevelEncoder: "upper"},
+		{CallerEncoder: "long"},
+		{DurationEncoder: "hours"},
+		{TimeZone: "Mars/Base"},
+	} {
+		assert.Panics(t, func() {
+			log.NewZapLog([]log.OutputConfig{{Writer: "console", FormatConfig: fc}})
+		})
+	}
+}
+
+func TestNewTimeEncoderInLocation(t *testing.T) {
+	date := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	loc := time.FixedZone("UTC+8", 8*3600)
+	cfg := zapcore.EncoderConfig{TimeKey: "T", EncodeTime: log.NewTimeEncoderInLocation("", loc)}
+	buf, err := zapcore.NewConsoleEncoder(cfg).EncodeEntry(zapcore.Entry{Time: date}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2006-01-02 23:04:05.000\n", buf.String())
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// Formatter creates the zapcore.Encoder of an output from its config.
+type Formatter func(c *OutputConfig) zapcore.Encoder
+
+var formatters = make(map[string]Formatter)
+
+// RegisterFormatter registers log output formatter, which is selected by OutputConfig.Formatter.
+func RegisterFormatter(name string, formatter Formatter) {
+	formatters[name] = formatter
+}
+
+// GetFormatter gets log output formatter, returns nil if not exist.
+func GetFormatter(name string) Formatter {
+	return formatters[name]
+}
+
+func newConsoleEncoder(c *OutputConfig) zapcore.Encoder {
+	return zapcore.NewConsoleEncoder(NewEncoderConfig(c))
+}
+
+func newJSONEncoder(c *OutputConfig) zapcore.Encoder {
+	return zapcore.NewJSONEncoder(NewEncoderConfig(c))
+}
+
+func newLogfmtEncoder(c *OutputConfig) zapcore.Encoder {
+	return NewLogfmtEncoder(NewEncoderConfig(c))
+}
+
+func newPatternEncoder(c *OutputConfig) zapcore.Encoder {
+	enc := NewPatternEncoder(NewEncoderConfig(c), c.FormatConfig.Pattern).(*patternEncoder)
+	enc.loc = timeZone(c)
+	return enc
+}
@@ -0,0 +1,42 @@
+package log_test
+
+import (
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRegisterFormatter(t *testing.T) {
+	for _, name := range []string{"console", "json", "logfmt", "pattern"} {
+		assert.NotNil(t, log.GetFormatter(name), name)
+	}
+	assert.Nil(t, log.GetFormatter("not_exist"))
+
+	var got *log.OutputConfig
+	log.RegisterFormatter("custom", func(c *log.OutputConfig) zapcore.Encoder {
+		got = c
+		cfg := log.NewEncoderConfig(c)
+		assert.Equal(t, "time", cfg.TimeKey)
+		return zapcore.NewJSONEncoder(cfg)
+	})
+	assert.NotNil(t, log.GetFormatter("custom"))
+	logger := log.NewZapLog([]log.OutputConfig{{
+		Writer:       "console",
+		Formatter:    "custom",
+		FormatConfig: log.FormatConfig{TimeKey: "time"},
+	}})
+	assert.NotNil(t, logger)
+	assert.Equal(t, "custom", got.Formatter)
+}
+
+func TestUnknownFormatter(t *testing.T) {
+	assert.PanicsWithValue(t, "log: formatter: not_exist no registered", func() {
+		log.NewZapLog([]log.OutputConfig{{Writer: "console", Formatter: "not_exist"}})
+	})
+	assert.NotPanics(t, func() {
+		log.NewZapLog([]log.OutputConfig{{Writer: "console"}})
+	})
+}
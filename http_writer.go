@@ -0,0 +1,300 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// body formats of the http writer.
+const (
+	HTTPBodyArray  = "array"
+	HTTPBodyNDJSON = "ndjson"
+)
+
+// HTTPClientConfig is the config of the http requests shared by the http based writers.
+type HTTPClientConfig struct {
+	// URL is the url to post logs to.
+	URL string `yaml:"url"`
+	// Headers are the extra headers of requests, like Authorization.
+	Headers map[string]string `yaml:"headers"`
+	// Gzip defines whether request bodies are compressed by gzip.
+	Gzip bool `yaml:"gzip"`
+	// Timeout is the timeout in milliseconds of a request, default as 5000.
+	Timeout int `yaml:"timeout"`
+	// MaxRetries is the max number of retries on network errors and 429 or 5xx responses, default
+	// as 3. A negative value disables retries.
+	MaxRetries int `yaml:"max_retries"`
+	// MinBackoff and MaxBackoff are the bounds in milliseconds of the exponential backoff between
+	// retries, default as 100 and 5000.
+	MinBackoff int `yaml:"min_backoff"`
+	MaxBackoff int `yaml:"max_backoff"`
+}
+
+// HTTPConfig is the config of the http writer, which is set in remote_config.
+type HTTPConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	BatchConfig      `yaml:",inline"`
+
+	// Body is the format of request bodies, array for a JSON array of entries, or ndjson for
+	// entries separated by newlines. Default as array.
+	Body string `yaml:"body"`
+	// SpillFile is the file which batches failing after all retries are appended to. They are
+	// dropped if it is empty.
+	SpillFile string `yaml:"spill_file"`
+}
+
+// HTTPWriterFactory is the http writer instance Factory.
+type HTTPWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *HTTPWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers http output writer. Entries are encoded by the json formatter
+// unless another formatter is configured.
+func (f *HTTPWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("http writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("http writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	hc := &HTTPConfig{}
+	if err := decodeRemoteConfig(cfg, hc); err != nil {
+		return fmt.Errorf("http writer remote config invalid: %v", err)
+	}
+	body := GetLogEncoderKey(HTTPBodyArray, hc.Body)
+	if body != HTTPBodyArray && body != HTTPBodyNDJSON {
+		return fmt.Errorf("http writer body %s invalid", hc.Body)
+	}
+	client, err := newHTTPClient(&hc.HTTPClientConfig)
+	if err != nil {
+		return fmt.Errorf("http writer %v", err)
+	}
+	s := &httpSender{client: client, ndjson: body == HTTPBodyNDJSON, spillFile: hc.SpillFile}
+	w := newBatchWriter("http writer "+hc.URL, hc.BatchConfig, s.send)
+
+	if cfg.Formatter == "" {
+		cfg.Formatter = FormatterJSON
+	}
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core, decoder.ZapLevel = zapcore.NewCore(newEncoder(cfg), w, lvl), lvl
+	decoder.Closer = closerFunc(func() error {
+		client.close()
+		return w.Close()
+	})
+	return nil
+}
+
+// httpSender sends batches of entries to the http writer endpoint.
+type httpSender struct {
+	client    *httpClient
+	ndjson    bool
+	spillFile string
+}
+
+func (s *httpSender) send(entries [][]byte) (int, error) {
+	var body bytes.Buffer
+	if !s.ndjson {
+		body.WriteByte('[')
+	}
+	for i, entry := range entries {
+		entry = bytes.TrimRight(entry, "\r\n")
+		if i > 0 && !s.ndjson {
+			body.WriteByte(',')
+		}
+		body.Write(entry)
+		if s.ndjson {
+			body.WriteByte('\n')
+		}
+	}
+	contentType := "application/json"
+	if s.ndjson {
+		contentType = "application/x-ndjson"
+	} else {
+		body.WriteByte(']')
+	}
+	if _, err := s.client.post(body.Bytes(), contentType); err != nil {
+		if s.spillFile != "" && spill(s.spillFile, entries) == nil {
+			return len(entries), err
+		}
+		return 0, &discardError{err: err, n: len(entries)}
+	}
+	return len(entries), nil
+}
+
+// spill appends entries to the file as lines.
+func spill(filename string, entries [][]byte) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	for _, entry := range entries {
+		data = append(data, bytes.TrimRight(entry, "\r\n")...)
+		data = append(data, '\n')
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// httpMaxResponseSize is the max size of response bodies read by httpClient.
+const httpMaxResponseSize = 8 << 20
+
+// httpClient posts request bodies, and retries with backoff on failures which may be temporary.
+type httpClient struct {
+	cfg      HTTPClientConfig
+	client   *http.Client
+	min, max time.Duration
+
+	done chan struct{} // closed by close to stop waiting for retries
+	once sync.Once
+}
+
+func newHTTPClient(c *HTTPClientConfig) (*httpClient, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url %q invalid", c.URL)
+	}
+	client := &httpClient{
+		cfg:    *c,
+		client: &http.Client{Timeout: msOrDefault(c.Timeout, 5000)},
+		min:    msOrDefault(c.MinBackoff, 100),
+		max:    msOrDefault(c.MaxBackoff, 5000),
+		done:   make(chan struct{}),
+	}
+	if client.cfg.MaxRetries == 0 {
+		client.cfg.MaxRetries = 3
+	}
+	if client.max < client.min {
+		client.max = client.min
+	}
+	return client, nil
+}
+
+// httpStatusError is the error of a response whose status is not 2xx.
+type httpStatusError struct {
+	code int
+	body string
+}
+
+// Error implements error.
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.code, e.body)
+}
+
+// post posts the body, and returns the body of the 2xx response, which may be partial if it fails
+// to be read. Only network errors and 429 or 5xx responses are retried.
+func (c *httpClient) post(body []byte, contentType string) ([]byte, error) {
+	encoding := ""
+	if c.cfg.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body)
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		body, encoding = buf.Bytes(), "gzip"
+	}
+
+	for retry := 0; ; retry++ {
+		resp, err := c.do(body, contentType, encoding)
+		if err == nil || !retryable(err) || retry >= c.cfg.MaxRetries {
+			return resp, err
+		}
+		if !c.wait(c.backoff(retry)) {
+			return resp, err
+		}
+	}
+}
+
+// wait waits for the delay before a retry. It returns false at once if the client is closed.
+func (c *httpClient) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// close stops retrying, so that closing the writer is not blocked by the backoff. The requests
+// sent after it are not retried.
+func (c *httpClient) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// backoff returns the delay before the retry, which doubles from MinBackoff up to MaxBackoff.
+func (c *httpClient) backoff(retry int) time.Duration {
+	d := c.min
+	for i := 0; i < retry && d < c.max; i++ {
+		d *= 2
+	}
+	if d > c.max {
+		d = c.max
+	}
+	return d
+}
+
+func (c *httpClient) do(body []byte, contentType, encoding string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	for k, v := range c.cfg.Headers {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, httpMaxResponseSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &httpStatusError{code: resp.StatusCode, body: string(bytes.TrimSpace(data))}
+	}
+	// The request is delivered by a 2xx response, so errors reading its body are not returned,
+	// which would send it again. Callers parsing the body fail on a partial one.
+	return data, nil
+}
+
+// retryable reports whether the request may succeed if it is sent again.
+func retryable(err error) bool {
+	if e, ok := err.(*httpStatusError); ok {
+		return e.code == http.StatusTooManyRequests || e.code >= 500
+	}
+	return true
+}
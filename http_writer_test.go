@@ -0,0 +1,225 @@
+package log_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newHTTPLogger creates a Logger with an http output configured by remote_config.
+func newHTTPLogger(t *testing.T, remote string) log.Logger {
+	return newWriterLogger(t, "{writer: http}", remote)
+}
+
+// httpRecorder records the bodies of requests, and responds the codes in order.
+type httpRecorder struct {
+	mu     sync.Mutex
+	codes  []int
+	bodies []string
+	header http.Header
+}
+
+func (r *httpRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	data, _ := ioutil.ReadAll(body)
+	r.bodies = append(r.bodies, string(data))
+	r.header = req.Header
+	code := http.StatusOK
+	if len(r.codes) > 0 {
+		code, r.codes = r.codes[0], r.codes[1:]
+	}
+	w.WriteHeader(code)
+}
+
+// httpMessages decodes the JSON array or ndjson body, and returns the message and fields of each
+// entry without time and caller.
+func httpMessages(t *testing.T, body string) []string {
+	var entries []map[string]interface{}
+	if strings.HasPrefix(body, "[") {
+		assert.Nil(t, json.Unmarshal([]byte(body), &entries))
+	} else {
+		s := bufio.NewScanner(strings.NewReader(body))
+		for s.Scan() {
+			var entry map[string]interface{}
+			assert.Nil(t, json.Unmarshal(s.Bytes(), &entry))
+			entries = append(entries, entry)
+		}
+	}
+	var messages []string
+	for _, entry := range entries {
+		delete(entry, "T")
+		delete(entry, "C")
+		data, err := json.Marshal(entry)
+		assert.Nil(t, err)
+		messages = append(messages, string(data))
+	}
+	return messages
+}
+
+func TestHTTPWriter(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	logger := newHTTPLogger(t, fmt.Sprintf(
+		"{url: %s, write_log_interval: 60000, gzip: true, headers: {Authorization: Bearer token}, "+
+			"batch_size: 2}", srv.URL))
+	logger.Infow("first", "uid", 1)
+	logger.Info("second")
+	logger.Info("third")
+	assert.Nil(t, logger.Sync())
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Len(t, rec.bodies, 2)
+	assert.Equal(t, []string{`{"L":"INFO","M":"first","uid":1}`, `{"L":"INFO","M":"second"}`},
+		httpMessages(t, rec.bodies[0]))
+	assert.Equal(t, []string{`{"L":"INFO","M":"third"}`}, httpMessages(t, rec.bodies[1]))
+	assert.Equal(t, "Bearer token", rec.header.Get("Authorization"))
+	assert.Equal(t, "application/json", rec.header.Get("Content-Type"))
+}
+
+func TestHTTPWriterRetry(t *testing.T) {
+	rec := &httpRecorder{codes: []int{http.StatusServiceUnavailable, http.StatusInternalServerError}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	logger := newHTTPLogger(t, fmt.Sprintf(
+		"{url: %s, write_log_interval: 60000, body: ndjson, min_backoff: 1}", srv.URL))
+	logger.Info("first")
+	logger.Info("second")
+	assert.Nil(t, logger.Sync())
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Len(t, rec.bodies, 3)
+	for _, body := range rec.bodies {
+		assert.True(t, strings.HasSuffix(body, "}\n"))
+		assert.Equal(t, []string{`{"L":"INFO","M":"first"}`, `{"L":"INFO","M":"second"}`},
+			httpMessages(t, body))
+	}
+	assert.Equal(t, "application/x-ndjson", rec.header.Get("Content-Type"))
+}
+
+func TestHTTPWriterDrop(t *testing.T) {
+	rec := &httpRecorder{codes: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	_, stderr := redirectStd(t, func() {
+		logger := newHTTPLogger(t, fmt.Sprintf("{url: %s, write_log_interval: 60000, min_backoff: 1}", srv.URL))
+		logger.Info("rejected")
+		assert.NotNil(t, logger.Sync())
+		logger.Info("accepted")
+		assert.Nil(t, logger.Sync())
+	})
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	// 4xx responses are not retried.
+	assert.Len(t, rec.bodies, 2)
+	assert.Equal(t, []string{`{"L":"INFO","M":"rejected"}`}, httpMessages(t, rec.bodies[0]))
+	assert.Equal(t, []string{`{"L":"INFO","M":"accepted"}`}, httpMessages(t, rec.bodies[1]))
+	assert.Contains(t, stderr, "log: http writer "+srv.URL+" dropped 1 entries\n")
+}
+
+func TestHTTPWriterSpill(t *testing.T) {
+	rec := &httpRecorder{codes: []int{500, 500, 500}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	spill := filepath.Join(t.TempDir(), "spill.log")
+	logger := newHTTPLogger(t, fmt.Sprintf(
+		"{url: %s, write_log_interval: 60000, max_retries: 2, min_backoff: 1, spill_file: %s}",
+		srv.URL, spill))
+	logger.Info("first")
+	logger.Info("second")
+	assert.NotNil(t, logger.Sync())
+
+	data, err := ioutil.ReadFile(spill)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"L":"INFO","M":"first"}`, `{"L":"INFO","M":"second"}`},
+		httpMessages(t, string(data)))
+	rec.mu.Lock()
+	assert.Len(t, rec.bodies, 3)
+	rec.mu.Unlock()
+}
+
+func TestHTTPWriterTruncatedResponse(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		// the body is shorter than its Content-Length, so reading it fails.
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok":`))
+	}))
+	defer srv.Close()
+
+	logger := newHTTPLogger(t, fmt.Sprintf("{url: %s, write_log_interval: 60000, min_backoff: 1}", srv.URL))
+	logger.Info("delivered")
+	assert.Nil(t, logger.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, requests)
+}
+
+func TestHTTPWriterCloseDuringBackoff(t *testing.T) {
+	rec := &httpRecorder{codes: []int{503, 503, 503}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	_, stderr := redirectStd(t, func() {
+		logger := newHTTPLogger(t, fmt.Sprintf(
+			"{url: %s, write_log_interval: 10, min_backoff: 10000}", srv.URL))
+		logger.Info("first")
+		assert.Eventually(t, func() bool {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			return len(rec.bodies) > 0
+		}, time.Second, 5*time.Millisecond)
+
+		start := time.Now()
+		assert.Nil(t, log.CloseOutputs(logger))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+	assert.Contains(t, stderr, "log: http writer "+srv.URL+" dropped 1 entries\n")
+}
+
+func TestHTTPWriterInvalidConfig(t *testing.T) {
+	for _, remote := range []string{
+		"{}",
+		"{url: ftp://127.0.0.1}",
+		"{url: http://127.0.0.1, body: xml}",
+	} {
+		assert.Panics(t, func() { newHTTPLogger(t, remote) }, remote)
+	}
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// sysMemfdCreate are the numbers of the memfd_create syscall, which is not in package syscall.
+var sysMemfdCreate = map[string]uintptr{
+	"386":     356,
+	"amd64":   319,
+	"arm":     385,
+	"arm64":   279,
+	"loong64": 279,
+	"riscv64": 279,
+	"ppc64":   360,
+	"ppc64le": 360,
+	"s390x":   350,
+}
+
+const (
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+	fAddSeals       = 1033
+	// fSeals seals the size and the content of a memfd, as journald requires.
+	fSeals = 0x1 | 0x2 | 0x4 | 0x8 // F_SEAL_SEAL | F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_WRITE
+)
+
+// journaldSendFile writes the entry to a sealed memfd, and sends its descriptor to journald. An
+// unlinked file in /dev/shm is used instead if memfd is unavailable.
+func journaldSendFile(conn *net.UnixConn, data []byte) error {
+	f, err := memfd(data)
+	if err != nil {
+		if f, err = ioutil.TempFile("/dev/shm", "tlog-journal-"); err != nil {
+			return err
+		}
+		_ = os.Remove(f.Name())
+		if _, err := f.Write(data); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	defer f.Close()
+	// WriteMsgUnix is not allowed on connected datagram sockets, so sendmsg is called directly.
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	if werr := rc.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return err != syscall.EAGAIN
+	}); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// memfd creates a sealed memfd with the data.
+func memfd(data []byte) (*os.File, error) {
+	trap, ok := sysMemfdCreate[runtime.GOARCH]
+	if !ok {
+		return nil, syscall.ENOSYS
+	}
+	name := []byte("tlog-journal\x00")
+	fd, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(&name[0])),
+		mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	f := os.NewFile(fd, "tlog-journal")
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, fSeals); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	return f, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package log
+
+import (
+	"errors"
+	"net"
+)
+
+// journaldSendFile is only supported on linux, where journald runs.
+func journaldSendFile(conn *net.UnixConn, data []byte) error {
+	return errors.New("journald writer entry too large")
+}
@@ -0,0 +1,247 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultJournaldSocket is the socket of the native journal protocol.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldConfig is the config of the journald writer, which is set in remote_config.
+type JournaldConfig struct {
+	// Socket is the path of the journal socket, default as DefaultJournaldSocket.
+	Socket string `yaml:"socket"`
+	// SyslogIdentifier is the SYSLOG_IDENTIFIER of entries, default as the name of the
+	// executable.
+	SyslogIdentifier string `yaml:"syslog_identifier"`
+}
+
+// JournaldWriterFactory is the journald writer instance Factory.
+type JournaldWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *JournaldWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers journald output writer. Entries are sent as journal fields by
+// the writer itself, so the formatter config does not apply.
+func (f *JournaldWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("journald writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("journald writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	jc := &JournaldConfig{}
+	if err := decodeRemoteConfig(cfg, jc); err != nil {
+		return fmt.Errorf("journald writer remote config invalid: %v", err)
+	}
+	w := &journaldWriter{socket: GetLogEncoderKey(DefaultJournaldSocket, jc.Socket)}
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core = &journaldCore{
+		LevelEnabler: lvl,
+		w:            w,
+		identifier:   GetLogEncoderKey(filepath.Base(os.Args[0]), jc.SyslogIdentifier),
+		fields:       zapcore.NewMapObjectEncoder(),
+	}
+	decoder.ZapLevel, decoder.Closer = lvl, w
+	return nil
+}
+
+// journaldCore is a zapcore.Core which sends each entry as a journal entry. The fields of the
+// entry are sent as journal fields named in upper case.
+type journaldCore struct {
+	zapcore.LevelEnabler
+	w          *journaldWriter
+	identifier string
+	fields     *zapcore.MapObjectEncoder // fields added by With
+}
+
+// With implements zapcore.Core.
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = c.withFields(fields)
+	return &clone
+}
+
+// withFields returns a copy of the fields added by With, with more fields added.
+func (c *journaldCore) withFields(fields []zapcore.Field) *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.fields.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc
+}
+
+// Check implements zapcore.Core.
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var b []byte
+	b = appendJournalField(b, "MESSAGE", ent.Message)
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(syslogSeverity(ent.Level)))
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", c.identifier)
+	if ent.LoggerName != "" {
+		b = appendJournalField(b, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		b = appendJournalField(b, "CODE_FILE", ent.Caller.File)
+		b = appendJournalField(b, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			b = appendJournalField(b, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		b = appendJournalField(b, "STACKTRACE", ent.Stack)
+	}
+	values := c.withFields(fields).Fields
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if name := journalFieldName(k); name != "" {
+			b = appendJournalField(b, name, fieldValueString(values[k]))
+		}
+	}
+	return c.w.write(b)
+}
+
+// Sync implements zapcore.Core.
+func (c *journaldCore) Sync() error {
+	return nil
+}
+
+// journalReservedFields are the journal fields written by the core or interpreted by journald,
+// which fields of logs are not allowed to overwrite.
+var journalReservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"MESSAGE_ID":        true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"SYSLOG_FACILITY":   true,
+	"SYSLOG_PID":        true,
+	"SYSLOG_TIMESTAMP":  true,
+	"SYSLOG_RAW":        true,
+	"LOGGER":            true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+	"STACKTRACE":        true,
+	"ERRNO":             true,
+	"TID":               true,
+	"DOCUMENTATION":     true,
+}
+
+// journalFieldName converts a field key to a journal field name, which consists of upper case
+// letters, digits and underscores, and starts with a letter. Names of reserved fields are
+// prefixed by "F_", like F_MESSAGE. It returns an empty name if nothing is left.
+func journalFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	s := strings.TrimLeft(string(name), "_0123456789")
+	if journalReservedFields[s] {
+		s = "F_" + s
+	}
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
+
+// appendJournalField appends a field in the native journal protocol. Values with newlines are
+// written in the binary form with their length.
+func appendJournalField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b = append(b, '\n')
+	b = append(b, size[:]...)
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journaldWriter sends entries to the journal socket. Entries too large for a datagram are
+// written to a memfd, whose descriptor is sent instead.
+type journaldWriter struct {
+	socket string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// write sends an entry, and retries once on a new connection on error.
+func (w *journaldWriter) write(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for retry := 0; retry < 2; retry++ {
+		if w.conn == nil {
+			addr := &net.UnixAddr{Name: w.socket, Net: "unixgram"}
+			if w.conn, err = net.DialUnix("unixgram", nil, addr); err != nil {
+				return err
+			}
+		}
+		if _, err = w.conn.Write(data); err == nil {
+			return nil
+		}
+		if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+			return journaldSendFile(w.conn, data)
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
+
+// Close closes the connection.
+func (w *journaldWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
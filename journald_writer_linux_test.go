@@ -0,0 +1,180 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newJournaldLogger creates a Logger with a journald output configured by remote_config.
+func newJournaldLogger(t *testing.T, remote string) log.Logger {
+	return newWriterLogger(t, "{writer: journald}", remote)
+}
+
+// listenJournal listens on a unixgram socket standing in for the journal socket.
+func listenJournal(t *testing.T, path string) *net.UnixConn {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	assert.Nil(t, err)
+	return conn
+}
+
+// readJournal reads a journal entry, which is either a datagram or a passed file descriptor.
+func readJournal(t *testing.T, conn *net.UnixConn) map[string]string {
+	buf, oob := make([]byte, 64*1024), make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if !assert.Nil(t, err) {
+		return nil
+	}
+	data := buf[:n]
+	if oobn > 0 {
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		assert.Nil(t, err)
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		assert.Nil(t, err)
+		f := os.NewFile(uintptr(fds[0]), "journal")
+		defer f.Close()
+		_, err = f.Seek(0, 0)
+		assert.Nil(t, err)
+		data, err = ioutil.ReadAll(f)
+		assert.Nil(t, err)
+	}
+	return parseJournal(t, data)
+}
+
+// parseJournal parses an entry in the native journal protocol.
+func parseJournal(t *testing.T, data []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		if !assert.True(t, i > 0) {
+			return fields
+		}
+		name := string(data[:i])
+		if data[i] == '=' {
+			end := bytes.IndexByte(data, '\n')
+			fields[name] = string(data[i+1 : end])
+			data = data[end+1:]
+			continue
+		}
+		size := int(binary.LittleEndian.Uint64(data[i+1 : i+9]))
+		fields[name] = string(data[i+9 : i+9+size])
+		assert.Equal(t, byte('\n'), data[i+9+size])
+		data = data[i+10+size:]
+	}
+	return fields
+}
+
+func TestJournaldWriter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn := listenJournal(t, socket)
+	defer conn.Close()
+
+	logger := newJournaldLogger(t, fmt.Sprintf("{socket: %s, syslog_identifier: app}", socket))
+	logger.With(
+		log.Field{Key: "request-id", Value: "r1"},
+		log.Field{Key: "_uid", Value: 7},
+		log.Field{Key: "detail", Value: "line1\nline2"},
+		log.Field{Key: "tags", Value: []string{"a", "b"}},
+	).Warn("hello")
+
+	fields := readJournal(t, conn)
+	assert.Contains(t, fields["CODE_FILE"], "journald_writer_linux_test.go")
+	assert.NotEmpty(t, fields["CODE_LINE"])
+	assert.Contains(t, fields["CODE_FUNC"], "TestJournaldWriter")
+	delete(fields, "CODE_FILE")
+	delete(fields, "CODE_LINE")
+	delete(fields, "CODE_FUNC")
+	assert.Equal(t, map[string]string{
+		"MESSAGE":           "hello",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "app",
+		"REQUEST_ID":        "r1",
+		"UID":               "7",
+		"DETAIL":            "line1\nline2",
+		"TAGS":              `["a","b"]`,
+	}, fields)
+
+	for level, priority := range map[string]string{"Debug": "7", "Info": "6", "Error": "3"} {
+		switch level {
+		case "Debug":
+			logger.Debug(level)
+		case "Info":
+			logger.Info(level)
+		case "Error":
+			logger.Error(level)
+		}
+		fields := readJournal(t, conn)
+		assert.Equal(t, level, fields["MESSAGE"])
+		assert.Equal(t, priority, fields["PRIORITY"])
+	}
+}
+
+func TestJournaldWriterReservedFields(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn := listenJournal(t, socket)
+	defer conn.Close()
+
+	logger := newJournaldLogger(t, fmt.Sprintf("{socket: %s, syslog_identifier: app}", socket))
+	log.Named(logger, "http").With(
+		log.Field{Key: "message", Value: "fake"},
+		log.Field{Key: "priority", Value: 0},
+		log.Field{Key: "syslog_identifier", Value: "other"},
+		log.Field{Key: "logger", Value: "db"},
+		log.Field{Key: "code.file", Value: "main.go"},
+	).Info("hello")
+
+	fields := readJournal(t, conn)
+	assert.Equal(t, "hello", fields["MESSAGE"])
+	assert.Equal(t, "6", fields["PRIORITY"])
+	assert.Equal(t, "app", fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "http", fields["LOGGER"])
+	assert.Contains(t, fields["CODE_FILE"], "journald_writer_linux_test.go")
+	assert.Equal(t, "fake", fields["F_MESSAGE"])
+	assert.Equal(t, "0", fields["F_PRIORITY"])
+	assert.Equal(t, "other", fields["F_SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "db", fields["F_LOGGER"])
+	assert.Equal(t, "main.go", fields["F_CODE_FILE"])
+}
+
+func TestJournaldWriterLargeEntry(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn := listenJournal(t, socket)
+	defer conn.Close()
+
+	logger := newJournaldLogger(t, fmt.Sprintf("{socket: %s}", socket))
+	msg := strings.Repeat("x", 4*1024*1024)
+	logger.Info(msg)
+
+	fields := readJournal(t, conn)
+	assert.Equal(t, msg, fields["MESSAGE"])
+	assert.Equal(t, filepath.Base(os.Args[0]), fields["SYSLOG_IDENTIFIER"])
+}
+
+func TestJournaldWriterReconnect(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn := listenJournal(t, socket)
+	logger := newJournaldLogger(t, fmt.Sprintf("{socket: %s}", socket))
+	logger.Info("first")
+	assert.Equal(t, "first", readJournal(t, conn)["MESSAGE"])
+
+	// journald restarts, and listens on a new socket at the same path.
+	assert.Nil(t, conn.Close())
+	assert.Nil(t, os.Remove(socket))
+	conn = listenJournal(t, socket)
+	defer conn.Close()
+	logger.Info("second")
+	assert.Equal(t, "second", readJournal(t, conn)["MESSAGE"])
+}
@@ -0,0 +1,226 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LoggerStatus is the status of a registered Logger reported by LevelHandler.
+type LoggerStatus struct {
+	Name    string         `json:"name"`
+	Outputs []OutputStatus `json:"outputs"`
+}
+
+// OutputStatus is the status of an output of a Logger reported by LevelHandler.
+type OutputStatus struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name,omitempty"`
+	Writer string `json:"writer"`
+	Level  string `json:"level"`
+	// RevertAt is the time when a temporary level change is reverted, empty if there is none.
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// levelRequest is the request to change the level of an output.
+type levelRequest struct {
+	Logger string `json:"logger"`
+	Output string `json:"output"`
+	Level  string `json:"level"`
+	// TTL is the duration like "10m" after which the previous level is restored. Empty means the
+	// change is permanent.
+	TTL string `json:"ttl"`
+}
+
+// levelRevert restores the level of an output when its timer fires.
+type levelRevert struct {
+	timer *time.Timer
+	level Level
+	at    time.Time
+}
+
+// levelHandler is the http.Handler returned by LevelHandler.
+type levelHandler struct {
+	mu      sync.Mutex
+	reverts map[string]*levelRevert // logger name/output index => revert
+}
+
+// LevelHandler returns an http.Handler to inspect and change log levels at runtime.
+//
+// GET lists every registered Logger with the writer type and current level of each output, and
+// the "logger" query parameter limits the list to one Logger.
+// PUT or POST changes the level of an output. Parameters are logger (default as "default"),
+// output (name or index), level and ttl, passed either as a JSON body or as form values. When ttl
+// is set, the previous level is restored after it expires.
+func LevelHandler() http.Handler {
+	return &levelHandler{reverts: make(map[string]*levelRevert)}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.list(w, r)
+	case http.MethodPut, http.MethodPost:
+		h.update(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (h *levelHandler) list(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("logger")
+	if name != "" {
+		l := Get(name)
+		if l == nil {
+			writeJSONError(w, http.StatusNotFound, fmt.Errorf("logger %s not exist", name))
+			return
+		}
+		writeJSON(w, http.StatusOK, h.status(name, l))
+		return
+	}
+
+	mu.RLock()
+	names := make([]string, 0, len(loggers))
+	for name := range loggers {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+	sort.Strings(names)
+
+	status := make([]LoggerStatus, 0, len(names))
+	for _, name := range names {
+		if l := Get(name); l != nil {
+			status = append(status, h.status(name, l))
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *levelHandler) update(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeLevelRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	level, ok := LevelNames[req.Level]
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("level %q invalid", req.Level))
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("ttl %q invalid", req.TTL))
+			return
+		}
+	}
+	l := Get(req.Logger)
+	if l == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("logger %s not exist", req.Logger))
+		return
+	}
+
+	// Use the output index as key, so that names and indexes of the same output share a revert.
+	key := req.Logger + "/" + req.Output
+	if zl := toZapLog(l); zl != nil {
+		i, err := outputIndex(zl.outputs(), req.Output)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, err)
+			return
+		}
+		key = req.Logger + "/" + strconv.Itoa(i)
+	}
+
+	h.mu.Lock()
+	previous := l.GetLevel(req.Output)
+	if revert, ok := h.reverts[key]; ok {
+		// Restore the level before the first temporary change, not an intermediate one.
+		revert.timer.Stop()
+		previous = revert.level
+		delete(h.reverts, key)
+	}
+	if err := l.SetLevelE(req.Output, level); err != nil {
+		h.mu.Unlock()
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+	if ttl > 0 {
+		revert := &levelRevert{level: previous, at: time.Now().Add(ttl)}
+		revert.timer = time.AfterFunc(ttl, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.reverts[key] != revert {
+				return
+			}
+			delete(h.reverts, key)
+			_ = l.SetLevelE(req.Output, revert.level)
+		})
+		h.reverts[key] = revert
+	}
+	h.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, h.status(req.Logger, l))
+}
+
+// status reports the status of Logger l registered as name.
+func (h *levelHandler) status(name string, l Logger) LoggerStatus {
+	status := LoggerStatus{Name: name, Outputs: []OutputStatus{}}
+	zl := toZapLog(l)
+	if zl == nil {
+		return status
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, o := range zl.outputs() {
+		s := OutputStatus{
+			Index:  i,
+			Name:   o.name,
+			Writer: o.writer,
+			Level:  LevelStrings[zapLevelToLevel[o.level.Level()]],
+		}
+		if revert, ok := h.reverts[name+"/"+strconv.Itoa(i)]; ok {
+			at := revert.at
+			s.RevertAt = &at
+		}
+		status.Outputs = append(status.Outputs, s)
+	}
+	return status
+}
+
+func decodeLevelRequest(r *http.Request) (*levelRequest, error) {
+	req := &levelRequest{}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, fmt.Errorf("request body invalid: %v", err)
+		}
+	} else {
+		req.Logger = r.FormValue("logger")
+		req.Output = r.FormValue("output")
+		req.Level = r.FormValue("level")
+		req.TTL = r.FormValue("ttl")
+	}
+	if req.Logger == "" {
+		req.Logger = defaultLoggerName
+	}
+	if req.Output == "" {
+		return nil, fmt.Errorf("output empty")
+	}
+	return req, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
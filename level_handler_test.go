@@ -0,0 +1,102 @@
+package log_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func doLevelRequest(t *testing.T, h http.Handler, method, target string, body string,
+	contentType string) (int, []byte) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger := log.NewZapLog([]log.OutputConfig{
+		{Writer: "console", Level: "info"},
+		{Name: "errors", Writer: "console", Level: "error"},
+	})
+	log.Register("level_handler", logger)
+	h := log.LevelHandler()
+
+	code, body := doLevelRequest(t, h, http.MethodGet, "/?logger=level_handler", "", "")
+	assert.Equal(t, http.StatusOK, code)
+	status := log.LoggerStatus{}
+	assert.Nil(t, json.Unmarshal(body, &status))
+	assert.Equal(t, "level_handler", status.Name)
+	assert.Equal(t, []log.OutputStatus{
+		{Index: 0, Writer: "console", Level: "info"},
+		{Index: 1, Name: "errors", Writer: "console", Level: "error"},
+	}, status.Outputs)
+
+	code, body = doLevelRequest(t, h, http.MethodGet, "/", "", "")
+	assert.Equal(t, http.StatusOK, code)
+	var all []log.LoggerStatus
+	assert.Nil(t, json.Unmarshal(body, &all))
+	var names []string
+	for _, s := range all {
+		names = append(names, s.Name)
+	}
+	assert.Contains(t, names, "default")
+	assert.Contains(t, names, "level_handler")
+
+	form := url.Values{"logger": {"level_handler"}, "output": {"errors"}, "level": {"debug"}}
+	code, _ = doLevelRequest(t, h, http.MethodPut, "/", form.Encode(), "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, log.LevelDebug, logger.GetLevel("errors"))
+
+	code, _ = doLevelRequest(t, h, http.MethodPost, "/",
+		`{"logger":"level_handler","output":"errors","level":"warn"}`, "application/json; charset=utf-8")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, log.LevelWarn, logger.GetLevel("errors"))
+
+	code, _ = doLevelRequest(t, h, http.MethodPost, "/",
+		`{"logger":"level_handler","output":"0","level":"nope"}`, "application/json")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = doLevelRequest(t, h, http.MethodPost, "/",
+		`{"logger":"level_handler","output":"missing","level":"debug"}`, "application/json")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = doLevelRequest(t, h, http.MethodPost, "/",
+		`{"logger":"missing","output":"0","level":"debug"}`, "application/json")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = doLevelRequest(t, h, http.MethodDelete, "/", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	logger := log.NewZapLog([]log.OutputConfig{{Name: "console", Writer: "console", Level: "info"}})
+	log.Register("level_handler_ttl", logger)
+	h := log.LevelHandler()
+
+	code, body := doLevelRequest(t, h, http.MethodPost, "/",
+		`{"logger":"level_handler_ttl","output":"console","level":"debug","ttl":"100ms"}`, "application/json")
+	assert.Equal(t, http.StatusOK, code)
+	status := log.LoggerStatus{}
+	assert.Nil(t, json.Unmarshal(body, &status))
+	assert.NotNil(t, status.Outputs[0].RevertAt)
+	assert.Equal(t, log.LevelDebug, logger.GetLevel("console"))
+
+	// a second temporary change still reverts to the original level.
+	code, _ = doLevelRequest(t, h, http.MethodPost, "/",
+		`{"logger":"level_handler_ttl","output":"0","level":"trace","ttl":"100ms"}`, "application/json")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, log.LevelTrace, logger.GetLevel("console"))
+
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel("console") == log.LevelInfo
+	}, time.Second, 10*time.Millisecond)
+}
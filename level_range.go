@@ -0,0 +1,92 @@
+package log
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levelRangeCore is a zapcore.Core which limits the levels of the core of an output to its
+// max_level, or to the levels in its level set. The min level is enforced by the core itself, so
+// that it follows SetLevel. It wraps the cores of all writers, including third-party ones.
+type levelRangeCore struct {
+	zapcore.Core
+	max zapcore.Level
+	set map[zapcore.Level]bool // nil if the levels are not listed
+}
+
+// newLevelRangeCore wraps core by max_level or levels of the config. It returns core itself if
+// neither is configured.
+func newLevelRangeCore(c *OutputConfig, core zapcore.Core) zapcore.Core {
+	if c.MaxLevel == "" && len(c.LevelSet) == 0 {
+		return core
+	}
+	r := &levelRangeCore{Core: core, max: zapcore.FatalLevel}
+	if c.MaxLevel != "" {
+		r.max = Levels[c.MaxLevel]
+	}
+	if len(c.LevelSet) > 0 {
+		r.set = make(map[zapcore.Level]bool, len(c.LevelSet))
+		for _, name := range c.LevelSet {
+			r.set[Levels[name]] = true
+		}
+	}
+	return r
+}
+
+// inRange reports whether the level is in the range.
+func (c *levelRangeCore) inRange(l zapcore.Level) bool {
+	if l > c.max {
+		return false
+	}
+	if c.set == nil {
+		return true
+	}
+	if l > zapcore.ErrorLevel {
+		// dpanic and panic are listed as fatal.
+		l = zapcore.FatalLevel
+	}
+	return c.set[l]
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *levelRangeCore) Enabled(l zapcore.Level) bool {
+	return c.inRange(l) && c.Core.Enabled(l)
+}
+
+// With implements zapcore.Core.
+func (c *levelRangeCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *levelRangeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.inRange(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// validateLevelRange checks max_level and levels of the output config.
+func validateLevelRange(c *OutputConfig) error {
+	if c.MaxLevel != "" && len(c.LevelSet) > 0 {
+		return errors.New("max_level and levels are exclusive")
+	}
+	if c.MaxLevel != "" {
+		max, ok := LevelNames[c.MaxLevel]
+		if !ok {
+			return errors.New("max_level " + c.MaxLevel + " invalid")
+		}
+		if levelToZapLevel[max] < Levels[c.Level] {
+			return errors.New("max_level " + c.MaxLevel + " below level " + c.Level)
+		}
+	}
+	for _, name := range c.LevelSet {
+		if _, ok := LevelNames[name]; !ok {
+			return errors.New("levels " + name + " invalid")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package log_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+	"github.com/hyperits/tlog/plugin"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// rangeWriter is a custom writer which records logs by zap observer, and knows nothing about the
+// level range of the config.
+type rangeWriter struct {
+	logs *observer.ObservedLogs
+}
+
+func (f *rangeWriter) Type() string { return "log" }
+
+func (f *rangeWriter) Setup(name string, dec plugin.Decoder) error {
+	decoder, ok := dec.(*log.Decoder)
+	if !ok {
+		return errors.New("invalid decoder")
+	}
+	lvl := zap.NewAtomicLevelAt(log.Levels[decoder.OutputConfig.Level])
+	decoder.Core, f.logs = observer.New(lvl)
+	decoder.ZapLevel = lvl
+	return nil
+}
+
+func TestLevelRangeFile(t *testing.T) {
+	dir := t.TempDir()
+	output := func(filename string) log.OutputConfig {
+		return log.OutputConfig{
+			Writer:       "file",
+			Level:        "debug",
+			Formatter:    "pattern",
+			FormatConfig: log.FormatConfig{Pattern: "%level %msg"},
+			WriteConfig:  log.WriteConfig{Filename: filepath.Join(dir, filename), WriteMode: log.WriteSync},
+		}
+	}
+	app, info, errs := output("app.log"), output("info.log"), output("error.log")
+	info.Level, info.MaxLevel = "info", "info"
+	errs.Name, errs.LevelSet = "errors", []string{"warn", "error", "fatal"}
+
+	logger := log.NewZapLog([]log.OutputConfig{app, info, errs})
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	logger.SetLevel("errors", log.LevelError)
+	logger.Warn("warn filtered")
+	assert.Nil(t, logger.Sync())
+
+	read := func(filename string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		assert.Nil(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "DEBUG debug\nINFO info\nWARN warn\nERROR error\nWARN warn filtered\n", read("app.log"))
+	assert.Equal(t, "INFO info\n", read("info.log"))
+	assert.Equal(t, "WARN warn\nERROR error\n", read("error.log"))
+}
+
+func TestLevelRangeCustomWriter(t *testing.T) {
+	w := &rangeWriter{}
+	log.RegisterWriter("level_range", w)
+	logger := log.NewZapLog([]log.OutputConfig{{
+		Writer:   "level_range",
+		LevelSet: []string{"debug", "error"},
+	}})
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Error("error")
+
+	var msgs []string
+	for _, e := range w.logs.All() {
+		msgs = append(msgs, e.Message)
+	}
+	assert.Equal(t, []string{"debug", "error"}, msgs)
+}
+
+func TestLevelRangeInvalid(t *testing.T) {
+	for _, c := range []log.OutputConfig{
+		{Writer: "console", MaxLevel: "verbose"},
+		{Writer: "console", Level: "error", MaxLevel: "info"},
+		{Writer: "console", MaxLevel: "error", LevelSet: []string{"info"}},
+		{Writer: "console", LevelSet: []string{"info", "verbose"}},
+	} {
+		assert.Panics(t, func() { log.NewZapLog([]log.OutputConfig{c}) })
+	}
+}
@@ -2,16 +2,25 @@
 package log
 
 import (
+	"context"
 	"fmt"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-// SetLevel sets log level for different output which may be "0", "1" or "2".
+// SetLevel sets log level for different output which may be the output name, or the output
+// index like "0", "1" or "2".
 func SetLevel(output string, level Level) {
 	GetDefaultLogger().SetLevel(output, level)
 }
 
+// SetLevelE sets log level for different output, and returns an error if the output or level
+// does not exist.
+func SetLevelE(output string, level Level) error {
+	return GetDefaultLogger().SetLevelE(output, level)
+}
+
 // GetLevel gets log level for different output.
 func GetLevel(output string) Level {
 	return GetDefaultLogger().GetLevel(output)
@@ -28,6 +37,93 @@ func With(fields ...Field) Logger {
 	return GetDefaultLogger().With(fields...)
 }
 
+// loggerKey is the context key under which NewContext stores the Logger.
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx which carries the given Logger. The XxxContext functions print
+// logs with it.
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the Logger carried by ctx, or the default Logger if ctx carries nothing.
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(Logger); ok && l != nil {
+			return l
+		}
+	}
+	return GetDefaultLogger()
+}
+
+// WithContextFields adds user defined fields to the Logger carried by ctx, and returns a copy of
+// ctx which carries the new Logger.
+func WithContextFields(ctx context.Context, fields ...Field) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(fields...))
+}
+
+// SpanContext is the trace id and the span id of a log in hex, like the W3C trace context.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// spanKey is the context key under which ContextWithSpan stores the SpanContext.
+type spanKey struct{}
+
+// spanFieldKey is the key of the field which carries the SpanContext of ctx to the writers.
+const spanFieldKey = "tlog.span_context"
+
+// ContextWithSpan returns a copy of ctx which carries the SpanContext. Logs printed by the
+// XxxContext functions with ctx take it to writers which export span contexts, like otlp.
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanFromContext returns the SpanContext carried by ctx, which is set by ContextWithSpan. It
+// may be replaced to extract the span contexts of tracing libraries, like OpenTelemetry.
+var SpanFromContext = func(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanKey{}).(SpanContext)
+	return sc, ok
+}
+
+// spanField returns the field which carries the SpanContext. Encoders skip it, and writers find
+// it by spanFromFields.
+func spanField(sc SpanContext) zapcore.Field {
+	return zapcore.Field{Key: spanFieldKey, Type: zapcore.SkipType, Interface: sc}
+}
+
+// spanFromFields returns the SpanContext carried by the fields.
+func spanFromFields(fields []zapcore.Field) (SpanContext, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == spanFieldKey && fields[i].Type == zapcore.SkipType {
+			if sc, ok := fields[i].Interface.(SpanContext); ok {
+				return sc, true
+			}
+		}
+	}
+	return SpanContext{}, false
+}
+
+// contextLogger returns the Logger used by the XxxContext functions, which carries the
+// SpanContext of ctx if there is one.
+// ZapLogWrapper is unwrapped so that XxxContext -> zapLog.Xxx -> zap.Logger.Xxx always has the
+// same nesting depth as the default caller skip expects.
+func contextLogger(ctx context.Context) Logger {
+	l := FromContext(ctx)
+	z := toZapLog(l)
+	if z == nil {
+		return l
+	}
+	if sc, ok := SpanFromContext(ctx); ok {
+		return &zapLog{cores: z.cores, logger: z.logger.With(spanField(sc))}
+	}
+	return z
+}
+
 // RedirectStdLog redirects std log to tlog logger as log level INFO.
 // After redirection, log flag is zero, the prefix is empty.
 // The returned function may be used to recover log flag and prefix, and redirect output to
@@ -41,15 +137,22 @@ func RedirectStdLog(logger Logger) (func(), error) {
 // The returned function may be used to recover log flag and prefix, and redirect output to
 // os.Stderr.
 func RedirectStdLogAt(logger Logger, level zapcore.Level) (func(), error) {
-	if l, ok := logger.(*zapLog); ok {
+	if l := toZapLog(logger); l != nil {
 		return zap.RedirectStdLogAt(l.logger, level)
 	}
-	if l, ok := logger.(*ZapLogWrapper); ok {
-		return zap.RedirectStdLogAt(l.l.logger, level)
-	}
 	return nil, fmt.Errorf("log: only supports redirecting std logs to tlog zap logger")
 }
 
+// Trace logs to TRACE log. Arguments are handled in the manner of fmt.Print.
+func Trace(args ...interface{}) {
+	GetDefaultLogger().Trace(args...)
+}
+
+// Tracef logs to TRACE log. Arguments are handled in the manner of fmt.Printf.
+func Tracef(format string, args ...interface{}) {
+	GetDefaultLogger().Tracef(format, args...)
+}
+
 // Debug logs to DEBUG log. Arguments are handled in the manner of fmt.Print.
 func Debug(args ...interface{}) {
 	GetDefaultLogger().Debug(args...)
@@ -101,3 +204,114 @@ func Fatal(args ...interface{}) {
 func Fatalf(format string, args ...interface{}) {
 	GetDefaultLogger().Fatalf(format, args...)
 }
+
+// Tracew logs a message to TRACE log with some additional context. The variadic key-value pairs
+// are handled in the manner of zap's SugaredLogger.
+func Tracew(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Tracew(msg, keysAndValues...)
+}
+
+// Debugw logs a message to DEBUG log with some additional context. The variadic key-value pairs
+// are handled in the manner of zap's SugaredLogger.
+func Debugw(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Debugw(msg, keysAndValues...)
+}
+
+// Infow logs a message to INFO log with some additional context. The variadic key-value pairs
+// are handled in the manner of zap's SugaredLogger.
+func Infow(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Infow(msg, keysAndValues...)
+}
+
+// Warnw logs a message to WARNING log with some additional context. The variadic key-value pairs
+// are handled in the manner of zap's SugaredLogger.
+func Warnw(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Warnw(msg, keysAndValues...)
+}
+
+// Errorw logs a message to ERROR log with some additional context. The variadic key-value pairs
+// are handled in the manner of zap's SugaredLogger.
+func Errorw(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Errorw(msg, keysAndValues...)
+}
+
+// Fatalw logs a message to FATAL log with some additional context. The variadic key-value pairs
+// are handled in the manner of zap's SugaredLogger.
+// All Fatal logs will exit by calling os.Exit(1).
+func Fatalw(msg string, keysAndValues ...interface{}) {
+	GetDefaultLogger().Fatalw(msg, keysAndValues...)
+}
+
+// TraceContext logs to TRACE log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Print.
+func TraceContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Trace(args...)
+}
+
+// TraceContextf logs to TRACE log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Printf.
+func TraceContextf(ctx context.Context, format string, args ...interface{}) {
+	contextLogger(ctx).Tracef(format, args...)
+}
+
+// DebugContext logs to DEBUG log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Print.
+func DebugContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Debug(args...)
+}
+
+// DebugContextf logs to DEBUG log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Printf.
+func DebugContextf(ctx context.Context, format string, args ...interface{}) {
+	contextLogger(ctx).Debugf(format, args...)
+}
+
+// InfoContext logs to INFO log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Print.
+func InfoContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Info(args...)
+}
+
+// InfoContextf logs to INFO log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Printf.
+func InfoContextf(ctx context.Context, format string, args ...interface{}) {
+	contextLogger(ctx).Infof(format, args...)
+}
+
+// WarnContext logs to WARNING log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Print.
+func WarnContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Warn(args...)
+}
+
+// WarnContextf logs to WARNING log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Printf.
+func WarnContextf(ctx context.Context, format string, args ...interface{}) {
+	contextLogger(ctx).Warnf(format, args...)
+}
+
+// ErrorContext logs to ERROR log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Print.
+func ErrorContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Error(args...)
+}
+
+// ErrorContextf logs to ERROR log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Printf.
+func ErrorContextf(ctx context.Context, format string, args ...interface{}) {
+	contextLogger(ctx).Errorf(format, args...)
+}
+
+// FatalContext logs to ERROR log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Print.
+// All Fatal logs will exit by calling os.Exit(1).
+// Implementations may also call os.Exit() with a non-zero exit code.
+func FatalContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Fatal(args...)
+}
+
+// FatalContextf logs to ERROR log with the Logger carried by ctx. Arguments are handled in the
+// manner of fmt.Printf.
+func FatalContextf(ctx context.Context, format string, args ...interface{}) {
+	contextLogger(ctx).Fatalf(format, args...)
+}
@@ -0,0 +1,39 @@
+package log_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestContextLogger(t *testing.T) {
+	core, ob := observer.New(zap.DebugLevel)
+	log.RegisterWriter("context", &withFieldsWriter{core: core})
+	zl := log.NewZapLog([]log.OutputConfig{{Writer: "context"}})
+
+	// ctx without logger falls back to the default logger.
+	assert.Equal(t, log.GetDefaultLogger(), log.FromContext(context.Background()))
+
+	ctx := log.NewContext(context.Background(), zl.With(log.Field{Key: "uid", Value: "10012"}))
+	ctx = log.WithContextFields(ctx, log.Field{Key: "cmd", Value: "login"})
+
+	log.DebugContext(ctx, "debug")
+	log.InfoContextf(ctx, "info %d", 1)
+	log.WarnContext(ctx, "warn")
+	log.ErrorContextf(ctx, "error %s", "x")
+
+	entries := ob.All()
+	assert.Equal(t, 4, len(entries))
+	assert.Equal(t, "info 1", entries[1].Message)
+	assert.Equal(t, zap.ErrorLevel, entries[3].Level)
+	for _, e := range entries {
+		assert.Equal(t, map[string]interface{}{"uid": "10012", "cmd": "login"}, e.ContextMap())
+		assert.Equal(t, "log_test.go", filepath.Base(e.Caller.File))
+	}
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// logfmtEncoder is a zapcore.Encoder which renders each entry as a logfmt line, such as
+// `T=2006-01-02T15:04:05.000 L=INFO C=tlog/server.go:42 M="hello world" uid=10012`.
+// Fields added by With are collected by the embedded fieldEncoder.
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	*fieldEncoder
+}
+
+// NewLogfmtEncoder creates a zapcore.Encoder in logfmt format. Keys are taken from cfg, and a key
+// set to zapcore.OmitKey omits the item. Values are quoted and escaped when needed, fields of nested
+// objects are flattened into dotted keys and arrays are rendered like [a,b].
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{
+		EncoderConfig: &cfg,
+		fieldEncoder:  newFieldEncoder(&cfg),
+	}
+}
+
+// Clone implements zapcore.Encoder.
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := *e
+	clone.fieldEncoder = e.fieldEncoder.clone()
+	return &clone
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := bufferPool.Get()
+	if e.TimeKey != zapcore.OmitKey && !ent.Time.IsZero() {
+		appendLogfmt(line, e.TimeKey, textValue(encodeTime(e.EncoderConfig, ent.Time)))
+	}
+	if e.LevelKey != zapcore.OmitKey {
+		appendLogfmt(line, e.LevelKey, encodeLevel(e.EncoderConfig, ent.Level))
+	}
+	if e.NameKey != zapcore.OmitKey && ent.LoggerName != "" {
+		appendLogfmt(line, e.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		if e.CallerKey != zapcore.OmitKey {
+			appendLogfmt(line, e.CallerKey, encodeCaller(e.EncoderConfig, ent.Caller))
+		}
+		if e.FunctionKey != zapcore.OmitKey {
+			appendLogfmt(line, e.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if e.MessageKey != zapcore.OmitKey {
+		appendLogfmt(line, e.MessageKey, ent.Message)
+	}
+
+	all := e.fieldEncoder
+	if len(fields) > 0 {
+		all = e.fieldEncoder.clone()
+		for _, f := range fields {
+			f.AddTo(all)
+		}
+	}
+	flattenFields("", all.fields, func(key string, value interface{}) {
+		appendLogfmt(line, key, textValue(value))
+	})
+
+	if ent.Stack != "" && e.StacktraceKey != zapcore.OmitKey {
+		appendLogfmt(line, e.StacktraceKey, ent.Stack)
+	}
+	if e.LineEnding != "" {
+		line.AppendString(e.LineEnding)
+	} else {
+		line.AppendString(zapcore.DefaultLineEnding)
+	}
+	return line, nil
+}
+
+// appendLogfmt appends a space separated key=value pair to line.
+func appendLogfmt(line *buffer.Buffer, key, value string) {
+	if line.Len() > 0 {
+		line.AppendByte(' ')
+	}
+	line.AppendString(logfmtKey(key))
+	line.AppendByte('=')
+	line.AppendString(quoteIfNeeded(value))
+}
+
+// logfmtKey replaces the characters which are not allowed in a logfmt key with '_'.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
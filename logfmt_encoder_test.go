@@ -0,0 +1,78 @@
+package log_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.TimeKey, cfg.LevelKey, cfg.NameKey, cfg.CallerKey = "time", "level", "logger", "caller"
+	cfg.MessageKey, cfg.StacktraceKey, cfg.FunctionKey = "msg", "stack", zapcore.OmitKey
+	enc := log.NewLogfmtEncoder(cfg)
+	zap.String("uid", "10012").AddTo(enc)
+	zap.Object("req", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("path", "/a b")
+		return enc.AddObject("peer", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddInt("port", 80)
+			return nil
+		}))
+	})).AddTo(enc)
+
+	ent := newTestEntry()
+	ent.LoggerName = "access"
+	ent.Message = "say \"hi\"\nbye"
+	ent.Stack = "main.main\n\tmain.go:1"
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.Strings("tags", []string{"a", "b c"}),
+		zap.Error(errors.New("io timeout")),
+		zap.String("bad key=", ""),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `time="2006-01-02 15:04:05.000" level=INFO logger=access caller=tlog/server.go:42 `+
+		`msg="say \"hi\"\nbye" uid=10012 req.path="/a b" req.peer.port=80 tags="[a,\"b c\"]" `+
+		`error="io timeout" bad_key_="" stack="main.main\n\tmain.go:1"`+"\n", buf.String())
+}
+
+func TestLogfmtEncoderOmitKeys(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.TimeKey, cfg.LevelKey, cfg.CallerKey, cfg.MessageKey = zapcore.OmitKey, "L", zapcore.OmitKey, "M"
+	enc := log.NewLogfmtEncoder(cfg).Clone()
+	buf, err := enc.EncodeEntry(newTestEntry(), []zapcore.Field{zap.Namespace("ns"), zap.Int("n", 1)})
+	assert.Nil(t, err)
+	assert.Equal(t, "L=INFO M=\"hello world\" ns.n=1\n", buf.String())
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	var caller string
+	stdout, _ := redirectStd(t, func() {
+		logger := log.NewZapLog([]log.OutputConfig{{
+			Writer:    "console",
+			Level:     "debug",
+			Formatter: "logfmt",
+			FormatConfig: log.FormatConfig{
+				TimeFmt: "now", TimeKey: "ts", MessageKey: "msg",
+			},
+		}})
+		tags := map[string]interface{}{"env": "prod", "ids": []int{1, 2}}
+		_, file, line, _ := runtime.Caller(0)
+		caller = fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+2)
+		logger.With(log.Field{Key: "uid", Value: 1}).Infow("logfmt formatter", "user", user{"tom", 3}, "tags", tags)
+		assert.Nil(t, logger.Sync())
+	})
+	assert.Equal(t, "ts=now L=INFO C="+caller+" msg=\"logfmt formatter\" uid=1 user.name=tom user.age=3 "+
+		"tags.env=prod tags.ids=[1,2]\n", stdout)
+}
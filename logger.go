@@ -48,11 +48,42 @@ type LoggerOptions struct {
 	LogLevel Level
 	Pattern  string
 	Writer   io.Writer
+	// Formatter is the name of the formatter, default as pattern if Pattern is set, or console.
+	Formatter string
 }
 
 // LoggerOption modifies the LoggerOptions.
 type LoggerOption func(*LoggerOptions)
 
+// WithLogLevel sets the level of the Logger created by NewLogger.
+func WithLogLevel(level Level) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.LogLevel = level
+	}
+}
+
+// WithPattern sets the line template of the pattern formatter, see NewPatternEncoder.
+func WithPattern(pattern string) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Pattern = pattern
+	}
+}
+
+// WithWriter sets the destination of logs. If it is a zapcore.WriteSyncer, its Sync is called
+// when the Logger is synced.
+func WithWriter(w io.Writer) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Writer = w
+	}
+}
+
+// WithFormatter sets the formatter, such as console, json, logfmt or pattern.
+func WithFormatter(formatter string) LoggerOption {
+	return func(o *LoggerOptions) {
+		o.Formatter = formatter
+	}
+}
+
 // Field is the user defined log field.
 type Field struct {
 	Key   string
@@ -88,12 +119,35 @@ type Logger interface {
 	// Fatalf logs to ERROR log. Arguments are handled in the manner of fmt.Printf.
 	Fatalf(format string, args ...interface{})
 
+	// Tracew logs a message to TRACE log with some additional context. The variadic key-value
+	// pairs are treated as they are in With.
+	Tracew(msg string, keysAndValues ...interface{})
+	// Debugw logs a message to DEBUG log with some additional context. The variadic key-value
+	// pairs are treated as they are in With.
+	Debugw(msg string, keysAndValues ...interface{})
+	// Infow logs a message to INFO log with some additional context. The variadic key-value
+	// pairs are treated as they are in With.
+	Infow(msg string, keysAndValues ...interface{})
+	// Warnw logs a message to WARNING log with some additional context. The variadic key-value
+	// pairs are treated as they are in With.
+	Warnw(msg string, keysAndValues ...interface{})
+	// Errorw logs a message to ERROR log with some additional context. The variadic key-value
+	// pairs are treated as they are in With.
+	Errorw(msg string, keysAndValues ...interface{})
+	// Fatalw logs a message to FATAL log with some additional context. The variadic key-value
+	// pairs are treated as they are in With.
+	// All Fatal logs will exit by calling os.Exit(1).
+	Fatalw(msg string, keysAndValues ...interface{})
+
 	// Sync calls the underlying Core's Sync method, flushing any buffered log entries.
 	// Applications should take care to call Sync before exiting.
 	Sync() error
 
-	// SetLevel set the output log level.
+	// SetLevel set the output log level. Output is either the name or the index of the output.
 	SetLevel(output string, level Level)
+	// SetLevelE set the output log level, and returns an error if the output or level does not
+	// exist.
+	SetLevelE(output string, level Level) error
 	// GetLevel get the output log level.
 	GetLevel(output string) Level
 	// WithFields set some user defined data to logs, such as uid, imei, etc.
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/hyperits/tlog/plugin"
+	"io"
 	"sync"
 
 	"go.uber.org/zap"
@@ -12,8 +13,23 @@ import (
 )
 
 func init() {
+	RegisterFormatter(FormatterConsole, newConsoleEncoder)
+	RegisterFormatter(FormatterJSON, newJSONEncoder)
+	RegisterFormatter(FormatterLogfmt, newLogfmtEncoder)
+	RegisterFormatter(FormatterPattern, newPatternEncoder)
 	RegisterWriter(OutputConsole, DefaultConsoleWriterFactory)
 	RegisterWriter(OutputFile, DefaultFileWriterFactory)
+	RegisterWriter(OutputStderr, DefaultStderrWriterFactory)
+	RegisterWriter(OutputWriter, DefaultIOWriterFactory)
+	RegisterWriter(OutputSyslog, DefaultSyslogWriterFactory)
+	RegisterWriter(OutputNet, DefaultNetWriterFactory)
+	RegisterWriter(OutputHTTP, DefaultHTTPWriterFactory)
+	RegisterWriter(OutputLoki, DefaultLokiWriterFactory)
+	RegisterWriter(OutputElasticsearch, DefaultElasticsearchWriterFactory)
+	RegisterWriter(OutputFluentd, DefaultFluentdWriterFactory)
+	RegisterWriter(OutputOTLP, DefaultOTLPWriterFactory)
+	RegisterWriter(OutputJournald, DefaultJournaldWriterFactory)
+	RegisterWriter(OutputMemory, DefaultMemoryWriterFactory)
 	Register(defaultLoggerName, NewZapLog(defaultConfig))
 	plugin.Register(defaultLoggerName, DefaultLogFactory)
 }
@@ -91,6 +107,12 @@ type Decoder struct {
 	OutputConfig *OutputConfig
 	Core         zapcore.Core
 	ZapLevel     zap.AtomicLevel
+	// Closer is optionally set by the writer to release its resources, such as opened files, when
+	// the output is replaced by Reload.
+	Closer io.Closer
+
+	// memory is set by the memory writer, so that GetMemoryWriter can look it up.
+	memory *MemoryWriter
 }
 
 // Decode decodes writer configuration, copy one.
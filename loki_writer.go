@@ -0,0 +1,345 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// compressions of the loki writer.
+const (
+	LokiSnappy = "snappy"
+	LokiGzip   = "gzip"
+	LokiNone   = "none"
+)
+
+// lokiPushPath is the path of the Loki push API.
+const lokiPushPath = "/loki/api/v1/push"
+
+// LokiConfig is the config of the loki writer, which is set in remote_config. The gzip option of
+// HTTPClientConfig is replaced by Compression.
+type LokiConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	BatchConfig      `yaml:",inline"`
+
+	// Labels are the static labels of streams. The placeholders ${hostname}, ${pid} and
+	// ${env:VAR} in values are expanded at setup time. Default as job with the name of the
+	// executable if both Labels and LabelFields are empty.
+	Labels map[string]string `yaml:"labels"`
+	// LabelFields are the fields lifted as labels, such as service. "level" lifts the level of
+	// entries.
+	LabelFields []string `yaml:"label_fields"`
+	// Compression is snappy, gzip or none. snappy pushes protobuf requests compressed by snappy,
+	// and gzip or none push JSON requests. Default as snappy.
+	Compression string `yaml:"compression"`
+	// TenantID is the tenant sent as the X-Scope-OrgID header in multi-tenant mode.
+	TenantID string `yaml:"tenant_id"`
+}
+
+// LokiWriterFactory is the loki writer instance Factory.
+type LokiWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *LokiWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers loki output writer. Lines are encoded by the json formatter
+// unless another formatter is configured.
+func (f *LokiWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("loki writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("loki writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	lc := &LokiConfig{}
+	if err := decodeRemoteConfig(cfg, lc); err != nil {
+		return fmt.Errorf("loki writer remote config invalid: %v", err)
+	}
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	core, closer, err := newLokiCore(cfg, lc, lvl)
+	if err != nil {
+		return fmt.Errorf("loki writer %v", err)
+	}
+	decoder.Core, decoder.ZapLevel, decoder.Closer = core, lvl, closer
+	return nil
+}
+
+func newLokiCore(cfg *OutputConfig, lc *LokiConfig,
+	lvl zap.AtomicLevel) (*lokiCore, io.Closer, error) {
+	compression := GetLogEncoderKey(LokiSnappy, lc.Compression)
+	if compression != LokiSnappy && compression != LokiGzip && compression != LokiNone {
+		return nil, nil, fmt.Errorf("compression %s invalid", lc.Compression)
+	}
+	labels := make(map[string]string, len(lc.Labels))
+	for k, v := range lc.Labels {
+		v, err := expandPlaceholders(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("label %s: %v", k, err)
+		}
+		labels[lokiLabelName(k)] = v
+	}
+	if len(labels) == 0 && len(lc.LabelFields) == 0 {
+		labels["job"] = filepath.Base(os.Args[0])
+	}
+	labelFields := make(map[string]string, len(lc.LabelFields))
+	for _, k := range lc.LabelFields {
+		labelFields[k] = lokiLabelName(k)
+	}
+
+	clientCfg := lc.HTTPClientConfig
+	if u, err := url.Parse(clientCfg.URL); err == nil && strings.TrimRight(u.Path, "/") == "" {
+		u.Path = lokiPushPath
+		clientCfg.URL = u.String()
+	}
+	clientCfg.Gzip = compression == LokiGzip
+	if lc.TenantID != "" {
+		headers := map[string]string{"X-Scope-OrgID": lc.TenantID}
+		for k, v := range clientCfg.Headers {
+			headers[k] = v
+		}
+		clientCfg.Headers = headers
+	}
+	client, err := newHTTPClient(&clientCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &lokiSender{client: client, protobuf: compression == LokiSnappy}
+	w := newBatchWriter("loki writer "+clientCfg.URL, lc.BatchConfig, s.send)
+
+	if cfg.Formatter == "" {
+		cfg.Formatter = FormatterJSON
+	}
+	return &lokiCore{
+		LevelEnabler: lvl,
+		enc:          newEncoder(cfg),
+		w:            w,
+		labelFields:  labelFields,
+		labels:       labels,
+	}, closerFunc(func() error {
+		client.close()
+		return w.Close()
+	}), nil
+}
+
+// lokiLabelName replaces the characters not allowed in label names by "_".
+func lokiLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		letter := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && !(i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// lokiCore is a zapcore.Core which pushes entries to Loki in the streams of their labels.
+type lokiCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *batchWriter
+
+	labelFields map[string]string // field key => label name
+	labels      map[string]string // static labels and labels lifted from With
+}
+
+// With implements zapcore.Core.
+func (c *lokiCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	clone.labels = c.liftLabels(fields, "")
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *lokiCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. The entry is queued as its labels in JSON, its time in
+// nanoseconds and its line, separated by newlines.
+func (c *lokiCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	labels, err := json.Marshal(c.liftLabels(fields, levelName(ent.Level)))
+	if err != nil {
+		return err
+	}
+	entry := make([]byte, 0, len(labels)+buf.Len()+21)
+	entry = append(entry, labels...)
+	entry = append(entry, '\n')
+	entry = strconv.AppendInt(entry, ent.Time.UnixNano(), 10)
+	entry = append(entry, '\n')
+	entry = append(entry, bytes.TrimRight(buf.Bytes(), "\r\n")...)
+	_, err = c.w.Write(entry)
+	return err
+}
+
+// Sync implements zapcore.Core.
+func (c *lokiCore) Sync() error {
+	return c.w.Sync()
+}
+
+// liftLabels returns the labels with the ones lifted from fields, and the level if it is not
+// empty.
+func (c *lokiCore) liftLabels(fields []zapcore.Field, level string) map[string]string {
+	labels := c.labels
+	copied := false
+	set := func(name, value string) {
+		if !copied {
+			labels = make(map[string]string, len(c.labels)+1)
+			for k, v := range c.labels {
+				labels[k] = v
+			}
+			copied = true
+		}
+		labels[name] = value
+	}
+	for _, f := range fields {
+		if name, ok := c.labelFields[f.Key]; ok {
+			set(name, fieldString(f))
+		}
+	}
+	if name, ok := c.labelFields["level"]; ok && level != "" {
+		set(name, level)
+	}
+	return labels
+}
+
+// levelName returns the name of a zap level like trace or info.
+func levelName(l zapcore.Level) string {
+	if lvl, ok := zapLevelToLevel[l]; ok {
+		return LevelStrings[lvl]
+	}
+	return l.String()
+}
+
+// fieldString returns the value of a field as a string.
+func fieldString(f zapcore.Field) string {
+	if f.Type == zapcore.StringType {
+		return f.String
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return fmt.Sprint(enc.Fields[f.Key])
+}
+
+// lokiStream is a stream of the Loki push API.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiSender pushes batches of entries queued by lokiCore.
+type lokiSender struct {
+	client   *httpClient
+	protobuf bool
+}
+
+func (s *lokiSender) send(entries [][]byte) (int, error) {
+	var (
+		streams []*lokiStream
+		index   = make(map[string]*lokiStream)
+	)
+	for _, entry := range entries {
+		parts := bytes.SplitN(entry, []byte{'\n'}, 3)
+		if len(parts) != 3 {
+			continue
+		}
+		stream, ok := index[string(parts[0])]
+		if !ok {
+			stream = &lokiStream{}
+			if err := json.Unmarshal(parts[0], &stream.Stream); err != nil {
+				continue
+			}
+			index[string(parts[0])] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{string(parts[1]), string(parts[2])})
+	}
+
+	var err error
+	if s.protobuf {
+		_, err = s.client.post(snappyEncode(lokiPushRequest(streams)), "application/x-protobuf")
+	} else {
+		var body []byte
+		if body, err = json.Marshal(map[string]interface{}{"streams": streams}); err == nil {
+			_, err = s.client.post(body, "application/json")
+		}
+	}
+	if err != nil {
+		return 0, &discardError{err: err, n: len(entries)}
+	}
+	return len(entries), nil
+}
+
+// lokiPushRequest encodes the streams as a logproto.PushRequest.
+func lokiPushRequest(streams []*lokiStream) []byte {
+	var req protoBuffer
+	for _, stream := range streams {
+		req.messageField(1, func(m *protoBuffer) {
+			m.stringField(1, lokiLabels(stream.Stream))
+			for _, v := range stream.Values {
+				ns, _ := strconv.ParseInt(v[0], 10, 64)
+				m.messageField(2, func(e *protoBuffer) {
+					e.messageField(1, func(ts *protoBuffer) {
+						ts.int64Field(1, ns/1e9)
+						ts.int64Field(2, ns%1e9)
+					})
+					e.stringField(2, v[1])
+				})
+			}
+		})
+	}
+	return req
+}
+
+// lokiLabels formats labels like {job="app", level="info"}.
+func lokiLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,216 @@
+package log_test
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newLokiLogger creates a Logger with a loki output configured by remote_config.
+func newLokiLogger(t *testing.T, remote string) log.Logger {
+	return newWriterLogger(t, `{writer: loki, formatter: pattern, formatter_config: {pattern: "%msg %fields"}}`,
+		remote)
+}
+
+// lokiPush is a push request received by fakeLoki, with streams keyed by their labels.
+type lokiPush struct {
+	header  http.Header
+	streams map[string][]string // labels => lines
+	times   []time.Time
+}
+
+// fakeLoki decodes push requests in JSON or in snappy compressed protobuf.
+type fakeLoki struct {
+	mu     sync.Mutex
+	path   string
+	pushes []lokiPush
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.path = r.URL.Path
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil && r.Header.Get("Content-Encoding") == "gzip" {
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(strings.NewReader(string(body))); err == nil {
+			body, err = ioutil.ReadAll(zr)
+		}
+	}
+	push := lokiPush{header: r.Header, streams: make(map[string][]string)}
+	if err == nil && r.Header.Get("Content-Type") == "application/x-protobuf" {
+		err = decodeLokiProtobuf(body, &push)
+	} else if err == nil {
+		err = decodeLokiJSON(body, &push)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.pushes = append(f.pushes, push)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeLokiJSON(body []byte, push *lokiPush) error {
+	var req struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return err
+	}
+	for _, s := range req.Streams {
+		labels, _ := json.Marshal(s.Stream)
+		for _, v := range s.Values {
+			var ns int64
+			fmt.Sscan(v[0], &ns)
+			push.times = append(push.times, time.Unix(0, ns))
+			push.streams[string(labels)] = append(push.streams[string(labels)], v[1])
+		}
+	}
+	return nil
+}
+
+func decodeLokiProtobuf(body []byte, push *lokiPush) error {
+	data, err := snappyDecode(body)
+	if err != nil {
+		return err
+	}
+	for _, stream := range protoFields(data)[1] {
+		fields := protoFields(stream)
+		labels := string(fields[1][0])
+		for _, entry := range fields[2] {
+			e := protoFields(entry)
+			ts := protoFields(e[1][0])
+			push.times = append(push.times, time.Unix(protoVarint(ts[1]), protoVarint(ts[2])))
+			push.streams[labels] = append(push.streams[labels], string(e[2][0]))
+		}
+	}
+	return nil
+}
+
+// snappyDecode decodes the snappy block format.
+func snappyDecode(src []byte) ([]byte, error) {
+	n, l := binary.Uvarint(src)
+	src = src[l:]
+	dst := make([]byte, 0, n)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			length := int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				size := length - 59
+				length = 0
+				for i := 0; i < size; i++ {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[size:]
+			}
+			length++
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+		case 1:
+			length := int(tag>>2&7) + 4
+			offset := int(tag>>5)<<8 | int(src[1])
+			dst = snappyCopy(dst, offset, length)
+			src = src[2:]
+		case 2:
+			length := int(tag>>2) + 1
+			offset := int(src[1]) | int(src[2])<<8
+			dst = snappyCopy(dst, offset, length)
+			src = src[3:]
+		default:
+			return nil, errors.New("snappy copy with 4 bytes offset not expected")
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, errors.New("snappy length mismatch")
+	}
+	return dst, nil
+}
+
+func snappyCopy(dst []byte, offset, length int) []byte {
+	for i := 0; i < length; i++ {
+		dst = append(dst, dst[len(dst)-offset])
+	}
+	return dst
+}
+
+func TestLokiWriterJSON(t *testing.T) {
+	loki := &fakeLoki{}
+	srv := httptest.NewServer(loki)
+	defer srv.Close()
+	os.Setenv("TLOG_TEST_ENV", "test")
+	defer os.Unsetenv("TLOG_TEST_ENV")
+
+	logger := newLokiLogger(t, fmt.Sprintf("{url: %s, compression: gzip, write_log_interval: 60000, "+
+		"labels: {job: app, env: '${env:TLOG_TEST_ENV}'}, label_fields: [level, service]}", srv.URL))
+	logger.With(log.Field{Key: "service", Value: "order"}).Info("hello")
+	logger.Warnw("oops", "uid", 1)
+	now := time.Now()
+	assert.Nil(t, logger.Sync())
+
+	loki.mu.Lock()
+	defer loki.mu.Unlock()
+	assert.Equal(t, "/loki/api/v1/push", loki.path)
+	assert.Len(t, loki.pushes, 1)
+	assert.Equal(t, map[string][]string{
+		`{"env":"test","job":"app","level":"info","service":"order"}`: {"hello service=order"},
+		`{"env":"test","job":"app","level":"warn"}`:                   {"oops uid=1"},
+	}, loki.pushes[0].streams)
+	assert.WithinDuration(t, now, loki.pushes[0].times[0], time.Second)
+}
+
+func TestLokiWriterProtobuf(t *testing.T) {
+	loki := &fakeLoki{}
+	srv := httptest.NewServer(loki)
+	defer srv.Close()
+
+	logger := newLokiLogger(t, fmt.Sprintf("{url: %s/custom/push, tenant_id: team-a, "+
+		"write_log_interval: 60000, write_log_size: 1048576, labels: {job: app}, label_fields: [level]}",
+		srv.URL))
+	long := strings.Repeat("abcdefgh", 10000)
+	logger.Debug("first")
+	logger.Error(long)
+	now := time.Now()
+	assert.Nil(t, logger.Sync())
+
+	loki.mu.Lock()
+	defer loki.mu.Unlock()
+	assert.Equal(t, "/custom/push", loki.path)
+	assert.Len(t, loki.pushes, 1)
+	assert.Equal(t, "team-a", loki.pushes[0].header.Get("X-Scope-OrgID"))
+	assert.Equal(t, map[string][]string{
+		`{job="app", level="debug"}`: {"first"},
+		`{job="app", level="error"}`: {long},
+	}, loki.pushes[0].streams)
+	assert.WithinDuration(t, now, loki.pushes[0].times[0], time.Second)
+}
+
+func TestLokiWriterInvalidConfig(t *testing.T) {
+	for _, remote := range []string{
+		"{}",
+		"{url: http://127.0.0.1:3100, compression: lz4}",
+		"{url: http://127.0.0.1:3100, labels: {job: '${unknown}'}}",
+	} {
+		assert.Panics(t, func() { newLokiLogger(t, remote) }, remote)
+	}
+}
@@ -0,0 +1,384 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultMemoryEntries is the number of entries kept by the memory writer when it is not
+// configured.
+const DefaultMemoryEntries = 1000
+
+// MemoryConfig is the config of the memory writer, which is set in remote_config.
+type MemoryConfig struct {
+	// MaxEntries is the max number of entries kept, default as DefaultMemoryEntries.
+	MaxEntries int `yaml:"max_entries"`
+	// MaxBytes is the max total size of the formatted entries kept, 0 for no limit. The latest
+	// entry is always kept, even if it is larger.
+	MaxBytes int `yaml:"max_bytes"`
+}
+
+// MemoryWriterFactory is the memory writer instance Factory.
+type MemoryWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *MemoryWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers memory output writer. The entries kept by an output are
+// queried by GetMemoryWriter or MemoryHandler.
+func (f *MemoryWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("memory writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("memory writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	mc := &MemoryConfig{}
+	if err := decodeRemoteConfig(cfg, mc); err != nil {
+		return fmt.Errorf("memory writer remote config invalid: %v", err)
+	}
+	if mc.MaxEntries < 0 || mc.MaxBytes < 0 {
+		return errors.New("memory writer max_entries and max_bytes must not be negative")
+	}
+	if mc.MaxEntries == 0 {
+		mc.MaxEntries = DefaultMemoryEntries
+	}
+	w := &MemoryWriter{maxEntries: mc.MaxEntries, maxBytes: mc.MaxBytes}
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core = &memoryCore{
+		LevelEnabler: lvl,
+		enc:          newEncoder(cfg),
+		w:            w,
+		fields:       zapcore.NewMapObjectEncoder(),
+	}
+	decoder.ZapLevel, decoder.Closer, decoder.memory = lvl, w, w
+	return nil
+}
+
+// MemoryEntry is an entry kept by the memory writer.
+type MemoryEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Logger  string                 `json:"logger,omitempty"`
+	Message string                 `json:"message"`
+	Caller  string                 `json:"caller,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	// Line is the entry encoded by the formatter of the output.
+	Line string `json:"line"`
+
+	level zapcore.Level
+}
+
+// MemoryQuery filters the entries kept by the memory writer. Zero values match all entries.
+type MemoryQuery struct {
+	// Level is the min level of entries.
+	Level Level
+	// Since and Until limit the time of entries to [Since, Until).
+	Since time.Time
+	Until time.Time
+	// Fields are the values of fields which entries must have. Arrays and objects are compared in
+	// JSON, and the other values in the format of fmt.Sprint.
+	Fields map[string]string
+	// Limit is the max number of the latest entries returned.
+	Limit int
+}
+
+// match reports whether the entry matches the query.
+func (q *MemoryQuery) match(e *MemoryEntry) bool {
+	if q.Level != LevelNil && e.level < levelToZapLevel[q.Level] {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	for k, want := range q.Fields {
+		v, ok := e.Fields[k]
+		if !ok || fieldValueString(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryWriter keeps the latest entries of a memory output in a ring buffer.
+type MemoryWriter struct {
+	maxEntries int
+	maxBytes   int
+
+	mu    sync.Mutex
+	buf   []MemoryEntry // grows up to maxEntries
+	head  int           // index of the oldest entry
+	n     int
+	bytes int
+}
+
+// GetMemoryWriter returns the MemoryWriter of an output of a registered Logger. The output may be
+// the output name, or the output index.
+func GetMemoryWriter(logger, output string) (*MemoryWriter, error) {
+	l := Get(logger)
+	if l == nil {
+		return nil, fmt.Errorf("log: logger %s not exist", logger)
+	}
+	zl := toZapLog(l)
+	if zl == nil {
+		return nil, fmt.Errorf("log: logger %s not based on zap", logger)
+	}
+	cores := zl.cores.Load().(*zapCores)
+	if w, ok := cores.memory[output]; ok {
+		return w, nil
+	}
+	if _, err := outputIndex(cores.outputs, output); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("log: output %s not a memory writer", output)
+}
+
+// Entries returns the entries matching the query in time order. A nil query matches all entries.
+// The Fields of entries must not be modified.
+func (w *MemoryWriter) Entries(q *MemoryQuery) []MemoryEntry {
+	if q == nil {
+		q = &MemoryQuery{}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entries := []MemoryEntry{}
+	for i := 0; i < w.n; i++ {
+		if e := &w.buf[(w.head+i)%len(w.buf)]; q.match(e) {
+			entries = append(entries, *e)
+		}
+	}
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries
+}
+
+// Reset drops all entries.
+func (w *MemoryWriter) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf, w.head, w.n, w.bytes = nil, 0, 0, 0
+}
+
+// Close implements io.Closer. The entries are kept, so that they can still be queried by holders
+// of the MemoryWriter after the Logger is reloaded. The reloaded Logger keeps its entries in a new
+// MemoryWriter, which is returned by GetMemoryWriter from then on.
+func (w *MemoryWriter) Close() error {
+	return nil
+}
+
+// push adds an entry, and drops the oldest entries over the limits.
+func (w *MemoryWriter) push(e MemoryEntry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case w.n < len(w.buf):
+		w.buf[(w.head+w.n)%len(w.buf)] = e
+		w.n++
+	case len(w.buf) < w.maxEntries:
+		w.buf, w.head = append(w.ordered(), e), 0
+		w.n++
+	default:
+		w.bytes -= len(w.buf[w.head].Line)
+		w.buf[w.head] = e
+		w.head = (w.head + 1) % len(w.buf)
+	}
+	w.bytes += len(e.Line)
+	for w.maxBytes > 0 && w.bytes > w.maxBytes && w.n > 1 {
+		w.bytes -= len(w.buf[w.head].Line)
+		w.buf[w.head] = MemoryEntry{}
+		w.head = (w.head + 1) % len(w.buf)
+		w.n--
+	}
+}
+
+// ordered returns the entries of the full buffer in time order.
+func (w *MemoryWriter) ordered() []MemoryEntry {
+	if w.head == 0 {
+		return w.buf
+	}
+	entries := make([]MemoryEntry, 0, 2*len(w.buf))
+	entries = append(entries, w.buf[w.head:]...)
+	return append(entries, w.buf[:w.head]...)
+}
+
+// memoryCore is a zapcore.Core which keeps each entry in a MemoryWriter.
+type memoryCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	w      *MemoryWriter
+	fields *zapcore.MapObjectEncoder // fields added by With
+}
+
+// With implements zapcore.Core.
+func (c *memoryCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	clone.fields = c.withFields(fields)
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+// withFields returns a copy of the fields added by With, with more fields added.
+func (c *memoryCore) withFields(fields []zapcore.Field) *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.fields.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc
+}
+
+// Check implements zapcore.Core.
+func (c *memoryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *memoryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	e := MemoryEntry{
+		Time:    ent.Time,
+		Level:   levelName(ent.Level),
+		Logger:  ent.LoggerName,
+		Message: ent.Message,
+		Line:    strings.TrimRight(buf.String(), "\r\n"),
+		level:   ent.Level,
+	}
+	buf.Free()
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	if f := c.withFields(fields).Fields; len(f) > 0 {
+		e.Fields = f
+	}
+	c.w.push(e)
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *memoryCore) Sync() error {
+	return nil
+}
+
+// MemoryHandler returns an http.Handler to dump the entries kept by a memory output.
+//
+// GET returns the entries in time order as a JSON array, or as the formatted lines if the
+// "format" query parameter is text. The other parameters are logger (default as "default"),
+// output (name or index), level (min level), since and until (RFC 3339 time), field (key=value,
+// which may be repeated) and limit (max number of the latest entries).
+func MemoryHandler() http.Handler {
+	return http.HandlerFunc(serveMemory)
+}
+
+func serveMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	params := r.URL.Query()
+	q, err := decodeMemoryQuery(params)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	logger := params.Get("logger")
+	if logger == "" {
+		logger = defaultLoggerName
+	}
+	if params.Get("output") == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("output empty"))
+		return
+	}
+	mw, err := GetMemoryWriter(logger, params.Get("output"))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+	entries := mw.Entries(q)
+	if params.Get("format") != "text" {
+		writeJSON(w, http.StatusOK, entries)
+		return
+	}
+	var b bytes.Buffer
+	for _, e := range entries {
+		b.WriteString(e.Line)
+		b.WriteByte('\n')
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(b.Bytes())
+}
+
+func decodeMemoryQuery(params url.Values) (*MemoryQuery, error) {
+	q := &MemoryQuery{}
+	if s := params.Get("level"); s != "" {
+		level, ok := LevelNames[s]
+		if !ok {
+			return nil, fmt.Errorf("level %q invalid", s)
+		}
+		q.Level = level
+	}
+	for _, p := range []struct {
+		key string
+		t   *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if s := params.Get(p.key); s != "" {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("%s %q invalid", p.key, s)
+			}
+			*p.t = t
+		}
+	}
+	for _, field := range params["field"] {
+		i := strings.Index(field, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("field %q invalid, key=value expected", field)
+		}
+		if q.Fields == nil {
+			q.Fields = make(map[string]string)
+		}
+		q.Fields[field[:i]] = field[i+1:]
+	}
+	if s := params.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("limit %q invalid", s)
+		}
+		q.Limit = limit
+	}
+	return q, nil
+}
@@ -0,0 +1,167 @@
+package log_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newMemoryLogger creates and registers a Logger with a memory output named "memory".
+func newMemoryLogger(t *testing.T, name, remote string) log.Logger {
+	logger := newWriterLogger(t, `{name: memory, writer: memory, formatter: pattern, `+
+		`formatter_config: {pattern: "%level %msg %fields"}}`, remote,
+		log.OutputConfig{Writer: "console", Level: "fatal"})
+	log.Register(name, logger)
+	return logger
+}
+
+func memoryMessages(entries []log.MemoryEntry) []string {
+	msgs := []string{}
+	for _, e := range entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+func TestMemoryWriter(t *testing.T) {
+	logger := newMemoryLogger(t, "memory_writer", "{max_entries: 3}")
+	w, err := log.GetMemoryWriter("memory_writer", "memory")
+	assert.Nil(t, err)
+	same, err := log.GetMemoryWriter("memory_writer", "1")
+	assert.Nil(t, err)
+	assert.True(t, w == same)
+
+	start := time.Now()
+	logger.Debug("first")
+	logger.With(log.Field{Key: "uid", Value: 7}).Info("second")
+	logger.With(log.Field{Key: "uid", Value: 8}).Warnf("third %d", 3)
+	logger.With(log.Field{Key: "uid", Value: 7}, log.Field{Key: "op", Value: "put"}).Error("fourth")
+
+	entries := w.Entries(nil)
+	assert.Equal(t, []string{"second", "third 3", "fourth"}, memoryMessages(entries))
+	assert.Equal(t, "INFO second uid=7", entries[0].Line)
+	assert.Equal(t, "info", entries[0].Level)
+	assert.Equal(t, map[string]interface{}{"uid": int64(7)}, entries[0].Fields)
+	assert.Contains(t, entries[0].Caller, "memory_writer_test.go")
+	assert.False(t, entries[0].Time.Before(start))
+
+	assert.Equal(t, []string{"third 3", "fourth"},
+		memoryMessages(w.Entries(&log.MemoryQuery{Level: log.LevelWarn})))
+	assert.Equal(t, []string{"second", "fourth"},
+		memoryMessages(w.Entries(&log.MemoryQuery{Fields: map[string]string{"uid": "7"}})))
+	assert.Equal(t, []string{"fourth"},
+		memoryMessages(w.Entries(&log.MemoryQuery{Fields: map[string]string{"uid": "7", "op": "put"}})))
+	assert.Equal(t, []string{"fourth"}, memoryMessages(w.Entries(&log.MemoryQuery{Limit: 1})))
+	assert.Equal(t, []string{},
+		memoryMessages(w.Entries(&log.MemoryQuery{Since: time.Now().Add(time.Hour)})))
+	assert.Equal(t, []string{}, memoryMessages(w.Entries(&log.MemoryQuery{Until: start})))
+
+	w.Reset()
+	assert.Empty(t, w.Entries(nil))
+	logger.Info("fifth")
+	assert.Equal(t, []string{"fifth"}, memoryMessages(w.Entries(nil)))
+}
+
+func TestMemoryWriterMaxBytes(t *testing.T) {
+	logger := newMemoryLogger(t, "memory_writer_bytes", "{max_entries: 10, max_bytes: 30}")
+	w, err := log.GetMemoryWriter("memory_writer_bytes", "memory")
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		logger.Infof("message %d", i) // "INFO message i" is 14 bytes.
+	}
+	assert.Equal(t, []string{"message 3", "message 4"}, memoryMessages(w.Entries(nil)))
+	logger.Info(strings.Repeat("x", 40))
+	assert.Equal(t, []string{strings.Repeat("x", 40)}, memoryMessages(w.Entries(nil)))
+	for i := 0; i < 12; i++ {
+		logger.Info("m") // "INFO m" is 6 bytes.
+	}
+	assert.Len(t, w.Entries(nil), 5)
+}
+
+func TestMemoryWriterInvalid(t *testing.T) {
+	assert.Panics(t, func() { newMemoryLogger(t, "memory_writer_invalid", "{max_entries: -1}") })
+
+	log.Register("memory_writer_console", log.NewZapLog([]log.OutputConfig{{Writer: "console"}}))
+	_, err := log.GetMemoryWriter("memory_writer_console", "0")
+	assert.NotNil(t, err)
+	_, err = log.GetMemoryWriter("memory_writer_none", "0")
+	assert.NotNil(t, err)
+}
+
+func TestMemoryWriterReload(t *testing.T) {
+	logger := newMemoryLogger(t, "memory_writer_reload", "{}")
+	old, err := log.GetMemoryWriter("memory_writer_reload", "memory")
+	assert.Nil(t, err)
+	logger.Info("before")
+
+	assert.Nil(t, log.Reload("memory_writer_reload", []log.OutputConfig{
+		{Writer: "console", Level: "fatal"},
+		{Name: "memory", Writer: "memory", Level: "debug"},
+	}))
+	logger.Info("after")
+	w, err := log.GetMemoryWriter("memory_writer_reload", "memory")
+	assert.Nil(t, err)
+	assert.False(t, w == old)
+	assert.Equal(t, []string{"before"}, memoryMessages(old.Entries(nil)))
+	assert.Equal(t, []string{"after"}, memoryMessages(w.Entries(nil)))
+
+	_, err = log.GetMemoryWriter("memory_writer_reload", "0")
+	assert.EqualError(t, err, "log: output 0 not a memory writer")
+	_, err = log.GetMemoryWriter("memory_writer_reload", "2")
+	assert.EqualError(t, err, "log: output 2 not exist")
+}
+
+func TestMemoryHandler(t *testing.T) {
+	logger := newMemoryLogger(t, "memory_handler", "{}")
+	logger.Info("first")
+	logger.With(log.Field{Key: "uid", Value: 7}).Error("second")
+	h := log.MemoryHandler()
+
+	get := func(params url.Values) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?"+params.Encode(), nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := get(url.Values{"logger": {"memory_handler"}, "output": {"memory"}})
+	assert.Equal(t, http.StatusOK, code)
+	var entries []log.MemoryEntry
+	assert.Nil(t, json.Unmarshal([]byte(body), &entries))
+	assert.Equal(t, []string{"first", "second"}, memoryMessages(entries))
+
+	code, body = get(url.Values{"logger": {"memory_handler"}, "output": {"memory"},
+		"level": {"error"}, "field": {"uid=7"}, "format": {"text"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ERROR second uid=7\n", body)
+
+	code, body = get(url.Values{"logger": {"memory_handler"}, "output": {"memory"},
+		"since": {time.Now().Add(time.Minute).Format(time.RFC3339)}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[]\n", body)
+
+	for _, params := range []url.Values{
+		{"logger": {"memory_handler"}},
+		{"logger": {"memory_handler"}, "output": {"memory"}, "level": {"verbose"}},
+		{"logger": {"memory_handler"}, "output": {"memory"}, "since": {"yesterday"}},
+		{"logger": {"memory_handler"}, "output": {"memory"}, "field": {"uid"}},
+		{"logger": {"memory_handler"}, "output": {"memory"}, "limit": {"-1"}},
+	} {
+		code, _ := get(params)
+		assert.Equal(t, http.StatusBadRequest, code, params.Encode())
+	}
+	code, _ = get(url.Values{"logger": {"memory_handler"}, "output": {"0"}})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,216 @@
+package log
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// appendMsgpack appends the MessagePack encoding of a value decoded from JSON. Map keys are
+// sorted, so that the encoding is stable.
+func appendMsgpack(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, i)
+		}
+		f, _ := v.Float64()
+		return appendMsgpackFloat(b, f)
+	case float64:
+		return appendMsgpackFloat(b, v)
+	case int64:
+		return appendMsgpackInt(b, v)
+	case string:
+		return appendMsgpackString(b, v)
+	case []interface{}:
+		b = appendMsgpackArrayHeader(b, len(v))
+		for _, e := range v {
+			b = appendMsgpack(b, e)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = appendMsgpackMapHeader(b, len(v))
+		for _, k := range keys {
+			b = appendMsgpackString(b, k)
+			b = appendMsgpack(b, v[k])
+		}
+		return b
+	default:
+		return appendMsgpackString(b, fmt.Sprint(v))
+	}
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return append(b, 0xd1, byte(i>>8), byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		b = append(b, 0xd2)
+		return appendUint32(b, uint32(i))
+	default:
+		b = append(b, 0xd3)
+		return appendUint32(appendUint32(b, uint32(uint64(i)>>32)), uint32(i))
+	}
+}
+
+func appendMsgpackFloat(b []byte, f float64) []byte {
+	bits := math.Float64bits(f)
+	b = append(b, 0xcb)
+	return appendUint32(appendUint32(b, uint32(bits>>32)), uint32(bits))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackBin(b []byte, data []byte) []byte {
+	switch n := len(data); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, data...)
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	default:
+		return appendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	default:
+		return appendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendMsgpackEventTime appends the EventTime extension of the Fluentd forward protocol.
+func appendMsgpackEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	return appendUint32(appendUint32(b, uint32(t.Unix())), uint32(t.Nanosecond()))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// readMsgpackStringMap reads a map of strings, such as the ack response of Fluentd. Values of
+// other types are not supported.
+func readMsgpackStringMap(r io.Reader) (map[string]string, error) {
+	head, err := readBytes(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case head[0]&0xf0 == 0x80:
+		n = int(head[0] & 0x0f)
+	case head[0] == 0xde:
+		size, err := readBytes(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(size))
+	default:
+		return nil, fmt.Errorf("msgpack map expected, got 0x%x", head[0])
+	}
+	m := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func readMsgpackString(r io.Reader) (string, error) {
+	head, err := readBytes(r, 1)
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case head[0]&0xe0 == 0xa0:
+		n = int(head[0] & 0x1f)
+	case head[0] == 0xd9 || head[0] == 0xc4:
+		size, err := readBytes(r, 1)
+		if err != nil {
+			return "", err
+		}
+		n = int(size[0])
+	case head[0] == 0xda || head[0] == 0xc5:
+		size, err := readBytes(r, 2)
+		if err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint16(size))
+	case head[0] == 0xdb || head[0] == 0xc6:
+		size, err := readBytes(r, 4)
+		if err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint32(size))
+	default:
+		return "", errors.New("msgpack string expected")
+	}
+	s, err := readBytes(r, n)
+	return string(s), err
+}
+
+func readBytes(r io.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
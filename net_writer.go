@@ -0,0 +1,212 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// framing of the net writer.
+const (
+	FramingNewline = "newline"
+	FramingLength  = "length"
+)
+
+// NetConfig is the config of the net writer, which is set in remote_config.
+type NetConfig struct {
+	// Network is tcp or udp, or their variants like tcp4.
+	Network string `yaml:"network"`
+	// Address is the address of the server, like "127.0.0.1:5170".
+	Address string `yaml:"address"`
+	// Framing is how entries are delimited, newline or length. newline terminates each entry by
+	// "\n", and length prefixes each entry by its length as a 4 bytes big endian integer. Default
+	// as newline.
+	Framing string `yaml:"framing"`
+	// Timeout is the timeout in milliseconds to connect and to write, default as 5000.
+	Timeout int `yaml:"timeout"`
+	// MinBackoff and MaxBackoff are the bounds in milliseconds of the exponential backoff between
+	// reconnections, default as 100 and 30000.
+	MinBackoff int `yaml:"min_backoff"`
+	MaxBackoff int `yaml:"max_backoff"`
+
+	BatchConfig `yaml:",inline"`
+}
+
+// NetWriterFactory is the net writer instance Factory.
+type NetWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *NetWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers net output writer.
+func (f *NetWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("net writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("net writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	nc := &NetConfig{}
+	if err := decodeRemoteConfig(cfg, nc); err != nil {
+		return fmt.Errorf("net writer remote config invalid: %v", err)
+	}
+	conn, err := newNetConn(nc)
+	if err != nil {
+		return err
+	}
+	w := newBatchWriter("net writer "+nc.Network+"://"+nc.Address, nc.BatchConfig, conn.send)
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core = zapcore.NewCore(newEncoder(cfg), &netFramer{w: w, length: conn.length}, lvl)
+	decoder.ZapLevel = lvl
+	decoder.Closer = closerFunc(func() error {
+		err := w.Close()
+		conn.close()
+		return err
+	})
+	return nil
+}
+
+// netFramer frames entries before they are batched.
+type netFramer struct {
+	w      *batchWriter
+	length bool
+}
+
+// Write implements io.Writer.
+func (f *netFramer) Write(p []byte) (int, error) {
+	entry := strings.TrimRight(string(p), "\r\n")
+	var frame []byte
+	if f.length {
+		frame = make([]byte, 4, 4+len(entry))
+		binary.BigEndian.PutUint32(frame, uint32(len(entry)))
+		frame = append(frame, entry...)
+	} else {
+		frame = append([]byte(entry), '\n')
+	}
+	if _, err := f.w.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer.
+func (f *netFramer) Sync() error {
+	return f.w.Sync()
+}
+
+// netConn is the connection of the net writer. It is only used by the goroutine of the
+// batchWriter.
+type netConn struct {
+	network  string
+	address  string
+	length   bool
+	datagram bool
+	timeout  time.Duration
+
+	conn    net.Conn
+	backoff reconnectBackoff
+}
+
+func newNetConn(c *NetConfig) (*netConn, error) {
+	if c.Address == "" {
+		return nil, errors.New("net writer address empty")
+	}
+	var datagram bool
+	switch c.Network {
+	case "tcp", "tcp4", "tcp6":
+	case "udp", "udp4", "udp6":
+		datagram = true
+	default:
+		return nil, fmt.Errorf("net writer network %s invalid", c.Network)
+	}
+	framing := GetLogEncoderKey(FramingNewline, c.Framing)
+	if framing != FramingNewline && framing != FramingLength {
+		return nil, fmt.Errorf("net writer framing %s invalid", c.Framing)
+	}
+	conn := &netConn{
+		network:  c.Network,
+		address:  c.Address,
+		length:   framing == FramingLength,
+		datagram: datagram,
+		timeout:  msOrDefault(c.Timeout, 5000),
+		backoff:  newReconnectBackoff(c.MinBackoff, c.MaxBackoff),
+	}
+	return conn, nil
+}
+
+// send writes framed entries. Over udp each entry is a datagram, and over tcp the entries are
+// written at once. On error the connection is closed and reestablished by a later send.
+func (c *netConn) send(entries [][]byte) (int, error) {
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return 0, err
+		}
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if c.datagram {
+		for i, entry := range entries {
+			if _, err := c.conn.Write(entry); err != nil {
+				c.fail()
+				return i, err
+			}
+		}
+		return len(entries), nil
+	}
+	var data []byte
+	for _, entry := range entries {
+		data = append(data, entry...)
+	}
+	if _, err := c.conn.Write(data); err != nil {
+		// A partially written batch is sent again as a whole on the new connection.
+		c.fail()
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// connect dials the server unless it is waiting for the backoff.
+func (c *netConn) connect() error {
+	if err := c.backoff.wait(); err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		c.fail()
+		return err
+	}
+	c.conn = conn
+	c.backoff.reset()
+	return nil
+}
+
+// fail closes the connection and doubles the backoff before the next reconnection.
+func (c *netConn) fail() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	c.backoff.fail()
+}
+
+// close closes the connection. It must be called after the batchWriter is closed.
+func (c *netConn) close() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+}
@@ -0,0 +1,152 @@
+package log_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newNetLogger creates a Logger with a net output configured by remote_config.
+func newNetLogger(t *testing.T, remote string) log.Logger {
+	return newWriterLogger(t, `{writer: net, formatter: pattern, formatter_config: {pattern: "%msg %fields"}}`,
+		remote)
+}
+
+func TestNetWriterTCPLengthFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	logger := newNetLogger(t, fmt.Sprintf("{network: tcp, address: %s, framing: length}", ln.Addr()))
+	logger.Infow("hello", "uid", 1)
+	logger.Info("world")
+	assert.Nil(t, logger.Sync())
+
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+	for _, want := range []string{"hello uid=1", "world"} {
+		var n uint32
+		assert.Nil(t, binary.Read(conn, binary.BigEndian, &n))
+		frame := make([]byte, n)
+		_, err := io.ReadFull(conn, frame)
+		assert.Nil(t, err)
+		assert.Equal(t, want, string(frame))
+	}
+}
+
+func TestNetWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	logger := newNetLogger(t, fmt.Sprintf("{network: udp, address: %s}", conn.LocalAddr()))
+	logger.Info("first")
+	logger.Info("second")
+	assert.Nil(t, logger.Sync())
+
+	buf := make([]byte, 1024)
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	for _, want := range []string{"first\n", "second\n"} {
+		n, _, err := conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		assert.Equal(t, want, string(buf[:n]))
+	}
+}
+
+func TestNetWriterReconnectBuffer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	assert.Nil(t, ln.Close())
+
+	var (
+		mu      sync.Mutex
+		dropped = make(map[string]uint64)
+	)
+	log.SetDroppedHook(func(writer string, n uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped[writer] += n
+	})
+	defer log.SetDroppedHook(nil)
+
+	_, stderr := redirectStd(t, func() {
+		// the buffer keeps 3 entries of 3 bytes while the server is down.
+		logger := newNetLogger(t, fmt.Sprintf("{network: tcp, address: %s, buffer_size: 9, "+
+			"write_log_interval: 10000, min_backoff: 5, max_backoff: 20}", addr))
+		for i := 1; i <= 5; i++ {
+			logger.Infof("a%d", i)
+		}
+		assert.NotNil(t, logger.Sync())
+
+		ln, err := net.Listen("tcp", addr)
+		assert.Nil(t, err)
+		defer ln.Close()
+		assert.Eventually(t, func() bool { return logger.Sync() == nil }, time.Second, 10*time.Millisecond)
+
+		conn, err := ln.Accept()
+		assert.Nil(t, err)
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for _, want := range []string{"a1\n", "a2\n", "a3\n"} {
+			line, err := r.ReadString('\n')
+			assert.Nil(t, err)
+			assert.Equal(t, want, line)
+		}
+	})
+	assert.Contains(t, stderr, "log: net writer tcp://"+addr+" dropped 2 entries\n")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]uint64{"net writer tcp://" + addr: 2}, dropped)
+}
+
+func TestNetWriterCloseDropped(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	assert.Nil(t, ln.Close())
+
+	var (
+		mu      sync.Mutex
+		dropped uint64
+	)
+	log.SetDroppedHook(func(writer string, n uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped += n
+	})
+	defer log.SetDroppedHook(nil)
+
+	_, stderr := redirectStd(t, func() {
+		logger := newNetLogger(t, fmt.Sprintf("{network: tcp, address: %s, write_log_interval: 60000}", addr))
+		logger.Info("first")
+		logger.Info("second")
+		assert.NotNil(t, log.CloseOutputs(logger))
+		logger.Info("closed")
+	})
+	assert.Contains(t, stderr, "log: net writer tcp://"+addr+" dropped 2 entries\n")
+	assert.Contains(t, stderr, "log: net writer tcp://"+addr+" dropped 1 entries\n")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, uint64(3), dropped)
+}
+
+func TestNetWriterInvalidConfig(t *testing.T) {
+	for _, remote := range []string{
+		"{network: tcp}",
+		"{network: unix, address: /tmp/log.sock}",
+		"{network: tcp, address: 127.0.0.1:5170, framing: crlf}",
+	} {
+		assert.Panics(t, func() { newNetLogger(t, remote) }, remote)
+	}
+}
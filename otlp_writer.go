@@ -0,0 +1,498 @@
+package log
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// encodings of the otlp writer.
+const (
+	OTLPProtobuf = "protobuf"
+	OTLPJSON     = "json"
+)
+
+const (
+	// otlpLogsPath is the path of the OTLP/HTTP logs endpoint.
+	otlpLogsPath = "/v1/logs"
+	// otlpScopeName is the instrumentation scope of the exported logs.
+	otlpScopeName = "github.com/hyperits/tlog"
+)
+
+// OTLPConfig is the config of the otlp writer, which is set in remote_config.
+type OTLPConfig struct {
+	HTTPClientConfig `yaml:",inline"`
+	BatchConfig      `yaml:",inline"`
+
+	// Encoding is the encoding of requests, protobuf or json, default as protobuf.
+	Encoding string `yaml:"encoding"`
+	// ResourceAttributes are the attributes of the resource, like service.name. The placeholders
+	// ${hostname}, ${pid} and ${env:VAR} in values are expanded at setup time. service.name is
+	// default as the name of the executable.
+	ResourceAttributes map[string]string `yaml:"resource_attributes"`
+	// TraceIDKey and SpanIDKey are the keys of the fields which hold the trace id and the span id
+	// in hex, default as trace_id and span_id. They populate the trace context of log records
+	// instead of attributes, if the logs are not printed with a SpanContext by the XxxContext
+	// functions.
+	TraceIDKey string `yaml:"trace_id_key"`
+	SpanIDKey  string `yaml:"span_id_key"`
+}
+
+// OTLPWriterFactory is the otlp writer instance Factory.
+type OTLPWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *OTLPWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers otlp output writer. Entries are converted to log records by
+// the writer itself, so the formatter config does not apply.
+func (f *OTLPWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("otlp writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("otlp writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	oc := &OTLPConfig{}
+	if err := decodeRemoteConfig(cfg, oc); err != nil {
+		return fmt.Errorf("otlp writer remote config invalid: %v", err)
+	}
+	encoding := GetLogEncoderKey(OTLPProtobuf, oc.Encoding)
+	if encoding != OTLPProtobuf && encoding != OTLPJSON {
+		return fmt.Errorf("otlp writer encoding %s invalid", oc.Encoding)
+	}
+	resource := map[string]interface{}{"service.name": filepath.Base(os.Args[0])}
+	for k, v := range oc.ResourceAttributes {
+		v, err := expandPlaceholders(v)
+		if err != nil {
+			return fmt.Errorf("otlp writer resource attribute %s: %v", k, err)
+		}
+		resource[k] = v
+	}
+
+	clientCfg := oc.HTTPClientConfig
+	if u, err := url.Parse(clientCfg.URL); err == nil && strings.TrimRight(u.Path, "/") == "" {
+		u.Path = otlpLogsPath
+		clientCfg.URL = u.String()
+	}
+	client, err := newHTTPClient(&clientCfg)
+	if err != nil {
+		return fmt.Errorf("otlp writer %v", err)
+	}
+	s := &otlpSender{client: client, json: encoding == OTLPJSON, resource: resource}
+	w := newBatchWriter("otlp writer "+clientCfg.URL, oc.BatchConfig, s.send)
+
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core = &otlpCore{
+		LevelEnabler: lvl,
+		w:            w,
+		json:         s.json,
+		traceIDKey:   GetLogEncoderKey("trace_id", oc.TraceIDKey),
+		spanIDKey:    GetLogEncoderKey("span_id", oc.SpanIDKey),
+		fields:       zapcore.NewMapObjectEncoder(),
+	}
+	decoder.ZapLevel = lvl
+	decoder.Closer = closerFunc(func() error {
+		client.close()
+		return w.Close()
+	})
+	return nil
+}
+
+// otlpSeverity maps zap levels to OTLP severity numbers.
+func otlpSeverity(l zapcore.Level) int64 {
+	switch {
+	case l < zapcore.DebugLevel:
+		return 1 // TRACE
+	case l == zapcore.DebugLevel:
+		return 5 // DEBUG
+	case l == zapcore.InfoLevel:
+		return 9 // INFO
+	case l == zapcore.WarnLevel:
+		return 13 // WARN
+	case l == zapcore.ErrorLevel:
+		return 17 // ERROR
+	default:
+		return 21 // FATAL
+	}
+}
+
+// otlpCore is a zapcore.Core which queues each entry as an encoded OTLP LogRecord.
+type otlpCore struct {
+	zapcore.LevelEnabler
+	w    *batchWriter
+	json bool
+
+	traceIDKey string
+	spanIDKey  string
+	fields     *zapcore.MapObjectEncoder // fields added by With
+	span       *SpanContext              // span context added by With
+}
+
+// With implements zapcore.Core.
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = c.withFields(fields)
+	if sc, ok := spanFromFields(fields); ok {
+		clone.span = &sc
+	}
+	return &clone
+}
+
+// withFields returns a copy of the fields added by With, with more fields added.
+func (c *otlpCore) withFields(fields []zapcore.Field) *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.fields.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc
+}
+
+// Check implements zapcore.Core.
+func (c *otlpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *otlpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	attrs := c.withFields(fields).Fields
+	// The span context of ctx goes first, and the fields are the fallback.
+	var traceID, spanID []byte
+	sc, ok := spanFromFields(fields)
+	if !ok && c.span != nil {
+		sc, ok = *c.span, true
+	}
+	if ok {
+		traceID, _ = otlpID(sc.TraceID, 16)
+		spanID, _ = otlpID(sc.SpanID, 8)
+	}
+	if id, ok := otlpID(attrs[c.traceIDKey], 16); ok {
+		if traceID == nil {
+			traceID = id
+		}
+		delete(attrs, c.traceIDKey)
+	}
+	if id, ok := otlpID(attrs[c.spanIDKey], 8); ok {
+		if spanID == nil {
+			spanID = id
+		}
+		delete(attrs, c.spanIDKey)
+	}
+	if ent.LoggerName != "" {
+		attrs["logger.name"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		attrs["code.filepath"] = ent.Caller.File
+		attrs["code.lineno"] = int64(ent.Caller.Line)
+		if ent.Caller.Function != "" {
+			attrs["code.function"] = ent.Caller.Function
+		}
+	}
+	if ent.Stack != "" {
+		attrs["exception.stacktrace"] = ent.Stack
+	}
+
+	record := &otlpRecord{
+		time:     uint64(ent.Time.UnixNano()),
+		severity: otlpSeverity(ent.Level),
+		text:     strings.ToUpper(levelName(ent.Level)),
+		body:     ent.Message,
+		attrs:    attrs,
+		traceID:  traceID,
+		spanID:   spanID,
+	}
+	var data []byte
+	if c.json {
+		var err error
+		if data, err = json.Marshal(record.jsonValue()); err != nil {
+			return err
+		}
+	} else {
+		data = record.protobuf()
+	}
+	_, err := c.w.Write(data)
+	return err
+}
+
+// Sync implements zapcore.Core.
+func (c *otlpCore) Sync() error {
+	return c.w.Sync()
+}
+
+// otlpID decodes a trace id or span id of n bytes in hex.
+func otlpID(v interface{}, n int) ([]byte, bool) {
+	s, ok := v.(string)
+	if !ok || len(s) != 2*n {
+		return nil, false
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil || bytes.Equal(id, make([]byte, n)) {
+		return nil, false
+	}
+	return id, true
+}
+
+// otlpRecord is an OTLP LogRecord.
+type otlpRecord struct {
+	time     uint64
+	severity int64
+	text     string
+	body     string
+	attrs    map[string]interface{}
+	traceID  []byte
+	spanID   []byte
+}
+
+// protobuf encodes the record as an opentelemetry.proto.logs.v1.LogRecord.
+func (r *otlpRecord) protobuf() []byte {
+	var b protoBuffer
+	b.fixed64Field(1, r.time)
+	b.int64Field(2, r.severity)
+	b.stringField(3, r.text)
+	b.messageField(5, func(m *protoBuffer) { otlpAnyValue(m, r.body) })
+	otlpAttributes(&b, 6, r.attrs)
+	b.bytesField(9, r.traceID)
+	b.bytesField(10, r.spanID)
+	b.fixed64Field(11, r.time)
+	return b
+}
+
+// jsonValue returns the record in the OTLP JSON encoding.
+func (r *otlpRecord) jsonValue() map[string]interface{} {
+	v := map[string]interface{}{
+		"timeUnixNano":         strconv.FormatUint(r.time, 10),
+		"observedTimeUnixNano": strconv.FormatUint(r.time, 10),
+		"severityNumber":       r.severity,
+		"severityText":         r.text,
+		"body":                 otlpJSONValue(r.body),
+		"attributes":           otlpJSONAttributes(r.attrs),
+	}
+	if r.traceID != nil {
+		v["traceId"] = hex.EncodeToString(r.traceID)
+	}
+	if r.spanID != nil {
+		v["spanId"] = hex.EncodeToString(r.spanID)
+	}
+	return v
+}
+
+// otlpAttributes writes attributes as repeated KeyValue fields sorted by key.
+func otlpAttributes(b *protoBuffer, field int, attrs map[string]interface{}) {
+	for _, k := range sortedKeys(attrs) {
+		v := attrs[k]
+		b.messageField(field, func(kv *protoBuffer) {
+			kv.stringField(1, k)
+			kv.messageField(2, func(m *protoBuffer) { otlpAnyValue(m, v) })
+		})
+	}
+}
+
+// otlpAnyValue writes the fields of an AnyValue.
+func otlpAnyValue(b *protoBuffer, v interface{}) {
+	switch v := otlpNormalize(v).(type) {
+	case string:
+		b.oneofString(1, v)
+	case bool:
+		var i uint64
+		if v {
+			i = 1
+		}
+		b.oneofVarint(2, i)
+	case int64:
+		b.oneofVarint(3, uint64(v))
+	case float64:
+		b.oneofDouble(4, v)
+	case []interface{}:
+		b.messageField(5, func(arr *protoBuffer) {
+			for _, e := range v {
+				arr.messageField(1, func(m *protoBuffer) { otlpAnyValue(m, e) })
+			}
+		})
+	case map[string]interface{}:
+		b.messageField(6, func(kvs *protoBuffer) { otlpAttributes(kvs, 1, v) })
+	}
+}
+
+func otlpJSONAttributes(attrs map[string]interface{}) []interface{} {
+	kvs := make([]interface{}, 0, len(attrs))
+	for _, k := range sortedKeys(attrs) {
+		kvs = append(kvs, map[string]interface{}{"key": k, "value": otlpJSONValue(attrs[k])})
+	}
+	return kvs
+}
+
+func otlpJSONValue(v interface{}) map[string]interface{} {
+	switch v := otlpNormalize(v).(type) {
+	case string:
+		return map[string]interface{}{"stringValue": v}
+	case bool:
+		return map[string]interface{}{"boolValue": v}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": v}
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			values = append(values, otlpJSONValue(e))
+		}
+		return map[string]interface{}{"arrayValue": map[string]interface{}{"values": values}}
+	case map[string]interface{}:
+		return map[string]interface{}{"kvlistValue": map[string]interface{}{"values": otlpJSONAttributes(v)}}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// otlpNormalize converts the values collected by zapcore.MapObjectEncoder to string, bool,
+// int64, float64, []interface{} or map[string]interface{}. Unsigned values over the int64 range
+// are converted to strings.
+func otlpNormalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string, bool, int64, float64, []interface{}, map[string]interface{}:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+		// Values over the int64 range are kept exactly as strings.
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	// Convert the other values like structs through JSON.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var decoded interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&decoded); err != nil {
+		return string(data)
+	}
+	return otlpNormalizeJSON(decoded)
+}
+
+// otlpNormalizeJSON converts the json.Number in values decoded from JSON.
+func otlpNormalizeJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = otlpNormalizeJSON(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = otlpNormalizeJSON(v[k])
+		}
+	}
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// otlpSender exports batches of log records queued by otlpCore.
+type otlpSender struct {
+	client   *httpClient
+	json     bool
+	resource map[string]interface{}
+}
+
+func (s *otlpSender) send(records [][]byte) (int, error) {
+	var (
+		body        []byte
+		contentType string
+	)
+	if s.json {
+		body, contentType = s.jsonRequest(records), "application/json"
+	} else {
+		body, contentType = s.protobufRequest(records), "application/x-protobuf"
+	}
+	if _, err := s.client.post(body, contentType); err != nil {
+		return 0, &discardError{err: err, n: len(records)}
+	}
+	return len(records), nil
+}
+
+// protobufRequest encodes an ExportLogsServiceRequest.
+func (s *otlpSender) protobufRequest(records [][]byte) []byte {
+	var req protoBuffer
+	req.messageField(1, func(rl *protoBuffer) {
+		rl.messageField(1, func(res *protoBuffer) { otlpAttributes(res, 1, s.resource) })
+		rl.messageField(2, func(sl *protoBuffer) {
+			sl.messageField(1, func(scope *protoBuffer) { scope.stringField(1, otlpScopeName) })
+			for _, record := range records {
+				sl.bytesField(2, record)
+			}
+		})
+	})
+	return req
+}
+
+// jsonRequest encodes an ExportLogsServiceRequest in JSON with the records encoded already.
+func (s *otlpSender) jsonRequest(records [][]byte) []byte {
+	resource, _ := json.Marshal(map[string]interface{}{"attributes": otlpJSONAttributes(s.resource)})
+	var b bytes.Buffer
+	b.WriteString(`{"resourceLogs":[{"resource":`)
+	b.Write(resource)
+	b.WriteString(`,"scopeLogs":[{"scope":{"name":"` + otlpScopeName + `"},"logRecords":[`)
+	b.Write(bytes.Join(records, []byte{','}))
+	b.WriteString(`]}]}]}`)
+	return b.Bytes()
+}
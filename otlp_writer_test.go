@@ -0,0 +1,347 @@
+package log_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newOTLPLogger creates a Logger with an otlp output configured by remote_config.
+func newOTLPLogger(t *testing.T, remote string) log.Logger {
+	return newWriterLogger(t, "{writer: otlp}", remote)
+}
+
+// otlpLog is a log record received by fakeCollector, in the same shape for both encodings.
+type otlpLog struct {
+	severity int64
+	text     string
+	body     interface{}
+	attrs    map[string]interface{}
+	traceID  string
+	spanID   string
+	hasTime  bool
+}
+
+// fakeCollector decodes export requests in protobuf or in JSON.
+type fakeCollector struct {
+	mu          sync.Mutex
+	path        string
+	contentType string
+	scope       string
+	resource    map[string]interface{}
+	logs        []otlpLog
+}
+
+func (f *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.path, f.contentType = r.URL.Path, r.Header.Get("Content-Type")
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil && f.contentType == "application/x-protobuf" {
+		f.decodeProtobuf(body)
+	} else if err == nil {
+		err = f.decodeJSON(body)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *fakeCollector) decodeProtobuf(body []byte) {
+	for _, rl := range protoFields(body)[1] {
+		rlFields := protoFields(rl)
+		f.resource = otlpProtoAttrs(protoFields(rlFields[1][0])[1])
+		for _, sl := range rlFields[2] {
+			slFields := protoFields(sl)
+			f.scope = string(protoFields(slFields[1][0])[1][0])
+			for _, record := range slFields[2] {
+				r := protoFields(record)
+				l := otlpLog{
+					severity: protoVarint(r[2]),
+					body:     otlpProtoValue(r[5][0]),
+					attrs:    otlpProtoAttrs(r[6]),
+					hasTime:  len(r[1]) > 0 && len(r[11]) > 0,
+				}
+				if len(r[3]) > 0 {
+					l.text = string(r[3][0])
+				}
+				if len(r[9]) > 0 {
+					l.traceID = hex.EncodeToString(r[9][0])
+				}
+				if len(r[10]) > 0 {
+					l.spanID = hex.EncodeToString(r[10][0])
+				}
+				f.logs = append(f.logs, l)
+			}
+		}
+	}
+}
+
+func otlpProtoAttrs(kvs [][]byte) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for _, kv := range kvs {
+		fields := protoFields(kv)
+		attrs[string(fields[1][0])] = otlpProtoValue(fields[2][0])
+	}
+	return attrs
+}
+
+func otlpProtoValue(data []byte) interface{} {
+	fields := protoFields(data)
+	switch {
+	case len(fields[1]) > 0:
+		return string(fields[1][0])
+	case len(fields[2]) > 0:
+		return protoVarint(fields[2]) != 0
+	case len(fields[3]) > 0:
+		return protoVarint(fields[3])
+	case len(fields[4]) > 0:
+		return math.Float64frombits(binary.LittleEndian.Uint64(fields[4][0]))
+	case len(fields[5]) > 0:
+		values := []interface{}{}
+		for _, v := range protoFields(fields[5][0])[1] {
+			values = append(values, otlpProtoValue(v))
+		}
+		return values
+	case len(fields[6]) > 0:
+		return otlpProtoAttrs(protoFields(fields[6][0])[1])
+	}
+	return nil
+}
+
+type otlpJSONKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (f *fakeCollector) decodeJSON(body []byte) error {
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []otlpJSONKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				LogRecords []struct {
+					TimeUnixNano         string                 `json:"timeUnixNano"`
+					ObservedTimeUnixNano string                 `json:"observedTimeUnixNano"`
+					SeverityNumber       int64                  `json:"severityNumber"`
+					SeverityText         string                 `json:"severityText"`
+					Body                 map[string]interface{} `json:"body"`
+					Attributes           []otlpJSONKeyValue     `json:"attributes"`
+					TraceID              string                 `json:"traceId"`
+					SpanID               string                 `json:"spanId"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return err
+	}
+	for _, rl := range req.ResourceLogs {
+		f.resource = otlpJSONAttrs(rl.Resource.Attributes)
+		for _, sl := range rl.ScopeLogs {
+			f.scope = sl.Scope.Name
+			for _, r := range sl.LogRecords {
+				_, err := strconv.ParseUint(r.TimeUnixNano, 10, 64)
+				f.logs = append(f.logs, otlpLog{
+					severity: r.SeverityNumber,
+					text:     r.SeverityText,
+					body:     otlpJSONValue(r.Body),
+					attrs:    otlpJSONAttrs(r.Attributes),
+					traceID:  r.TraceID,
+					spanID:   r.SpanID,
+					hasTime:  err == nil && r.ObservedTimeUnixNano == r.TimeUnixNano,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func otlpJSONAttrs(kvs []otlpJSONKeyValue) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for _, kv := range kvs {
+		attrs[kv.Key] = otlpJSONValue(kv.Value)
+	}
+	return attrs
+}
+
+func otlpJSONValue(v map[string]interface{}) interface{} {
+	for k, value := range v {
+		switch k {
+		case "intValue":
+			i, _ := strconv.ParseInt(value.(string), 10, 64)
+			return i
+		case "arrayValue":
+			values := []interface{}{}
+			for _, e := range value.(map[string]interface{})["values"].([]interface{}) {
+				values = append(values, otlpJSONValue(e.(map[string]interface{})))
+			}
+			return values
+		case "kvlistValue":
+			attrs := make(map[string]interface{})
+			for _, e := range value.(map[string]interface{})["values"].([]interface{}) {
+				kv := e.(map[string]interface{})
+				attrs[kv["key"].(string)] = otlpJSONValue(kv["value"].(map[string]interface{}))
+			}
+			return attrs
+		default:
+			return value
+		}
+	}
+	return nil
+}
+
+func TestOTLPWriter(t *testing.T) {
+	for _, encoding := range []string{"protobuf", "json"} {
+		t.Run(encoding, func(t *testing.T) {
+			collector := &fakeCollector{}
+			srv := httptest.NewServer(collector)
+			defer srv.Close()
+
+			os.Setenv("OTLP_TEST_ENV", "test")
+			defer os.Unsetenv("OTLP_TEST_ENV")
+			logger := newOTLPLogger(t, fmt.Sprintf("{url: %s, encoding: %s, write_log_interval: 60000, "+
+				"resource_attributes: {service.name: app, deployment.environment: '${env:OTLP_TEST_ENV}'}}",
+				srv.URL, encoding))
+			logger.With(
+				log.Field{Key: "uid", Value: 7},
+				log.Field{Key: "seq", Value: uint64(3)},
+				log.Field{Key: "hash", Value: uint64(math.MaxUint64)},
+			).Infof("hello %s", "otlp")
+			logger.With(
+				log.Field{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+				log.Field{Key: "span_id", Value: "00f067aa0ba902b7"},
+				log.Field{Key: "ok", Value: true},
+				log.Field{Key: "ratio", Value: 0.5},
+				log.Field{Key: "tags", Value: []string{"a", "b"}},
+			).Error("failed")
+			logger.With(log.Field{Key: "trace_id", Value: "invalid"}).Debug("debug")
+			assert.Nil(t, logger.Sync())
+
+			collector.mu.Lock()
+			defer collector.mu.Unlock()
+			assert.Equal(t, "/v1/logs", collector.path)
+			contentType := "application/x-protobuf"
+			if encoding == "json" {
+				contentType = "application/json"
+			}
+			assert.Equal(t, contentType, collector.contentType)
+			assert.Equal(t, "github.com/hyperits/tlog", collector.scope)
+			assert.Equal(t, map[string]interface{}{
+				"service.name":           "app",
+				"deployment.environment": "test",
+			}, collector.resource)
+
+			if !assert.Len(t, collector.logs, 3) {
+				return
+			}
+			for i := range collector.logs {
+				l := &collector.logs[i]
+				assert.True(t, l.hasTime)
+				assert.Contains(t, l.attrs["code.filepath"], "otlp_writer_test.go")
+				assert.NotZero(t, l.attrs["code.lineno"])
+				delete(l.attrs, "code.filepath")
+				delete(l.attrs, "code.lineno")
+				delete(l.attrs, "code.function")
+				l.hasTime = false
+			}
+			assert.Equal(t, otlpLog{severity: 9, text: "INFO", body: "hello otlp",
+				attrs: map[string]interface{}{
+					"uid":  int64(7),
+					"seq":  int64(3),
+					"hash": "18446744073709551615",
+				}}, collector.logs[0])
+			assert.Equal(t, otlpLog{severity: 17, text: "ERROR", body: "failed",
+				attrs: map[string]interface{}{
+					"ok":    true,
+					"ratio": 0.5,
+					"tags":  []interface{}{"a", "b"},
+				},
+				traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+				spanID:  "00f067aa0ba902b7",
+			}, collector.logs[1])
+			assert.Equal(t, otlpLog{severity: 5, text: "DEBUG", body: "debug",
+				attrs: map[string]interface{}{"trace_id": "invalid"}}, collector.logs[2])
+		})
+	}
+}
+
+func TestOTLPWriterSpanContext(t *testing.T) {
+	collector := &fakeCollector{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	logger := newOTLPLogger(t, fmt.Sprintf("{url: %s, write_log_interval: 60000}", srv.URL))
+	ctx := log.NewContext(context.Background(), logger.With(
+		log.Field{Key: "trace_id", Value: "0af7651916cd43dd8448eb211c80319c"},
+		log.Field{Key: "span_id", Value: "b7ad6b7169203331"},
+	))
+	log.InfoContext(ctx, "fields")
+	spanCtx := log.ContextWithSpan(ctx, log.SpanContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+	})
+	log.WarnContextf(spanCtx, "span %d", 1)
+	assert.Nil(t, logger.Sync())
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if !assert.Len(t, collector.logs, 2) {
+		return
+	}
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", collector.logs[0].traceID)
+	assert.Equal(t, "b7ad6b7169203331", collector.logs[0].spanID)
+	assert.Equal(t, "span 1", collector.logs[1].body)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", collector.logs[1].traceID)
+	assert.Equal(t, "00f067aa0ba902b7", collector.logs[1].spanID)
+	for _, l := range collector.logs {
+		assert.Contains(t, l.attrs["code.filepath"], "otlp_writer_test.go")
+		assert.NotContains(t, l.attrs, "trace_id")
+		assert.NotContains(t, l.attrs, "tlog.span_context")
+	}
+}
+
+func TestOTLPWriterDrop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	_, stderr := redirectStd(t, func() {
+		logger := newOTLPLogger(t, fmt.Sprintf("{url: %s/otlp/v1/logs, write_log_interval: 60000}", srv.URL))
+		logger.Info("first")
+		logger.Info("second")
+		assert.NotNil(t, logger.Sync())
+	})
+	assert.Contains(t, stderr, "log: otlp writer "+srv.URL+"/otlp/v1/logs dropped 2 entries\n")
+}
+
+func TestOTLPWriterInvalidConfig(t *testing.T) {
+	for _, remote := range []string{
+		"{}",
+		"{url: 'http://127.0.0.1:4318', encoding: grpc}",
+		"{url: 'http://127.0.0.1:4318', resource_attributes: {host: '${unknown}'}}",
+	} {
+		assert.Panics(t, func() { newOTLPLogger(t, remote) }, remote)
+	}
+}
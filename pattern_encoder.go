@@ -0,0 +1,316 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultPattern is the pattern of the pattern formatter when FormatConfig.Pattern is empty.
+const DefaultPattern = "%time %level %caller %msg %fields"
+
+var bufferPool = buffer.NewPool()
+
+// patternItem is either a literal text or a directive of a pattern.
+type patternItem struct {
+	literal string
+	blank   bool   // literal text of only spaces and tabs
+	verb    string // empty for literal text
+	arg     string
+	left    bool // pad on the right to align left
+	width   int  // minimum width, 0 means no padding
+	max     int  // maximum width, 0 means no truncation
+}
+
+// patternVerbs are the directives supported by the pattern formatter.
+var patternVerbs = map[string]bool{
+	"time":   true,
+	"level":  true,
+	"name":   true,
+	"caller": true,
+	"func":   true,
+	"msg":    true,
+	"fields": true,
+	"field":  true,
+	"stack":  true,
+}
+
+// parsePattern parses a pattern like "%time [%-5level] %caller %msg %fields".
+// A directive is "%[-][width][.max]verb[{arg}]", and "%%" is a literal "%". Unknown directives are
+// kept as literal text.
+func parsePattern(pattern string) []patternItem {
+	var (
+		items   []patternItem
+		literal strings.Builder
+	)
+	flush := func() {
+		if literal.Len() > 0 {
+			text := literal.String()
+			items = append(items, patternItem{literal: text, blank: strings.Trim(text, " \t") == ""})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(pattern); {
+		if pattern[i] != '%' {
+			literal.WriteByte(pattern[i])
+			i++
+			continue
+		}
+		if i+1 < len(pattern) && pattern[i+1] == '%' {
+			literal.WriteByte('%')
+			i += 2
+			continue
+		}
+		item, n := parseDirective(pattern[i+1:])
+		if n == 0 {
+			literal.WriteByte('%')
+			i++
+			continue
+		}
+		flush()
+		items = append(items, item)
+		i += n + 1
+	}
+	flush()
+	return items
+}
+
+// parseDirective parses a directive after "%". It returns the length of the directive, or 0 if s
+// does not start with a known directive.
+func parseDirective(s string) (patternItem, int) {
+	item := patternItem{}
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		item.left = true
+		i++
+	}
+	item.width, i = parseNumber(s, i)
+	if i < len(s) && s[i] == '.' {
+		item.max, i = parseNumber(s, i+1)
+	}
+	start := i
+	for i < len(s) && s[i] >= 'a' && s[i] <= 'z' {
+		i++
+	}
+	item.verb = s[start:i]
+	if !patternVerbs[item.verb] {
+		return patternItem{}, 0
+	}
+	if i < len(s) && s[i] == '{' {
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return patternItem{}, 0
+		}
+		item.arg = s[i+1 : i+end]
+		i += end + 1
+	}
+	return item, i
+}
+
+func parseNumber(s string, i int) (int, int) {
+	start := i
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n, _ := strconv.Atoi(s[start:i])
+	return n, i
+}
+
+// patternEncoder is a zapcore.Encoder which renders each entry from a pattern.
+// Fields added by With are collected by the embedded fieldEncoder.
+type patternEncoder struct {
+	*zapcore.EncoderConfig
+	*fieldEncoder
+	items    []patternItem
+	hasStack bool
+	loc      *time.Location // time zone of %time{layout}
+}
+
+// NewPatternEncoder creates a zapcore.Encoder which renders entries from the pattern, such as
+// "%time [%-5level] %caller %msg %fields". The directives are:
+//
+//	%time        time encoded by cfg.EncodeTime, or %time{layout} formatted by the layout
+//	%level       level encoded by cfg.EncodeLevel
+//	%name        logger name
+//	%caller      caller encoded by cfg.EncodeCaller
+//	%func        caller function name
+//	%msg         message
+//	%fields      fields as key=value, or only the listed ones like %fields{uid,cmd}
+//	%field{key}  value of a single field
+//	%stack       stack trace, which is appended on a new line if not in the pattern
+//
+// A directive may be written as %[-][width][.max]verb: width pads the text with spaces to align it
+// right, or left with "-", and max truncates the text to at most max characters.
+func NewPatternEncoder(cfg zapcore.EncoderConfig, pattern string) zapcore.Encoder {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	enc := &patternEncoder{
+		EncoderConfig: &cfg,
+		fieldEncoder:  newFieldEncoder(&cfg),
+		items:         parsePattern(pattern),
+		loc:           time.Local,
+	}
+	for _, item := range enc.items {
+		if item.verb == "stack" {
+			enc.hasStack = true
+		}
+	}
+	return enc
+}
+
+// Clone implements zapcore.Encoder.
+func (e *patternEncoder) Clone() zapcore.Encoder {
+	clone := *e
+	clone.fieldEncoder = e.fieldEncoder.clone()
+	return &clone
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *patternEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	all := e.fieldEncoder
+	if len(fields) > 0 {
+		all = e.fieldEncoder.clone()
+		for _, f := range fields {
+			f.AddTo(all)
+		}
+	}
+
+	line := bufferPool.Get()
+	// blank literals and empty directives from pending on are only written before other text, so
+	// that the separators of empty directives at the end of line are dropped.
+	pending := -1
+	for i, item := range e.items {
+		text := item.literal
+		if item.verb != "" {
+			text = item.format(e.render(item, ent, all))
+		}
+		if text == "" || item.blank {
+			if pending < 0 {
+				pending = i
+			}
+			continue
+		}
+		e.appendPending(line, pending, i)
+		pending = -1
+		line.AppendString(text)
+	}
+	if n := len(e.items); pending >= 0 && e.items[n-1].verb == "" {
+		// the pattern ends with a literal, which is kept.
+		e.appendPending(line, pending, n)
+	}
+
+	if ent.Stack != "" && !e.hasStack && e.StacktraceKey != zapcore.OmitKey {
+		line.AppendByte('\n')
+		line.AppendString(ent.Stack)
+	}
+	if e.LineEnding != "" {
+		line.AppendString(e.LineEnding)
+	} else {
+		line.AppendString(zapcore.DefaultLineEnding)
+	}
+	return line, nil
+}
+
+// appendPending appends the blank literals of items[from:to], where the directives are empty.
+func (e *patternEncoder) appendPending(line *buffer.Buffer, from, to int) {
+	if from < 0 {
+		return
+	}
+	for _, item := range e.items[from:to] {
+		if item.verb == "" {
+			line.AppendString(item.literal)
+		}
+	}
+}
+
+// render renders a directive without padding.
+func (e *patternEncoder) render(item patternItem, ent zapcore.Entry, fields *fieldEncoder) string {
+	switch item.verb {
+	case "time":
+		if item.arg != "" {
+			return ent.Time.In(e.loc).Format(item.arg)
+		}
+		return textValue(encodeTime(e.EncoderConfig, ent.Time))
+	case "level":
+		return encodeLevel(e.EncoderConfig, ent.Level)
+	case "name":
+		return ent.LoggerName
+	case "caller":
+		return encodeCaller(e.EncoderConfig, ent.Caller)
+	case "func":
+		return ent.Caller.Function
+	case "msg":
+		return ent.Message
+	case "fields":
+		return renderFields(fields.fields, item.arg)
+	case "field":
+		var text string
+		flattenFields("", fields.fields, func(key string, value interface{}) {
+			if key == item.arg {
+				text = textValue(value)
+			}
+		})
+		return text
+	case "stack":
+		return ent.Stack
+	default:
+		return ""
+	}
+}
+
+// renderFields renders fields as space separated key=value. If keys is not empty, only the fields
+// listed in the comma separated keys, or nested under them, are rendered.
+func renderFields(fields []encodedField, keys string) string {
+	var subset []string
+	if keys != "" {
+		subset = strings.Split(keys, ",")
+		for i := range subset {
+			subset[i] = strings.TrimSpace(subset[i])
+		}
+	}
+	var b strings.Builder
+	flattenFields("", fields, func(key string, value interface{}) {
+		if subset != nil && !matchKey(subset, key) {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(quoteIfNeeded(key))
+		b.WriteByte('=')
+		b.WriteString(quoteIfNeeded(textValue(value)))
+	})
+	return b.String()
+}
+
+// matchKey reports whether the dotted key is one of keys or nested under one of them.
+func matchKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// format truncates and pads text according to the directive.
+func (item patternItem) format(text string) string {
+	n := utf8.RuneCountInString(text)
+	if item.max > 0 && n > item.max {
+		text = string([]rune(text)[:item.max])
+		n = item.max
+	}
+	if n >= item.width {
+		return text
+	}
+	pad := strings.Repeat(" ", item.width-n)
+	if item.left {
+		return text + pad
+	}
+	return pad + text
+}
@@ -0,0 +1,115 @@
+package log_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestEntry() zapcore.Entry {
+	return zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.Local),
+		Message: "hello world",
+		Caller:  zapcore.NewEntryCaller(0, "/src/tlog/server.go", 42, true),
+	}
+}
+
+func newTestEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		EncodeLevel:    log.CapitalLevelEncoder,
+		EncodeTime:     log.NewTimeEncoder(""),
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}
+}
+
+func TestPatternEncoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+	}{
+		{"default", "", "2006-01-02 15:04:05.000 INFO tlog/server.go:42 hello world uid=10012 " +
+			"cmd=\"log in\" req.id=7\n"},
+		{"padding", "[%-5level] [%6level] %.5msg|", "[INFO ] [  INFO] hello|\n"},
+		{"fields subset", "%msg %fields{cmd,req}", "hello world cmd=\"log in\" req.id=7\n"},
+		{"single field", "%field{uid}:%field{req.id}:%field{none}", "10012:7:\n"},
+		{"time layout", "%time{15:04:05} 100%% %unknown", "15:04:05 100% %unknown\n"},
+		{"trailing empty", "%msg %name", "hello world\n"},
+		{"trailing empties", "%msg\t%name %func", "hello world\n"},
+		{"padded last", "%msg %-6level", "hello world INFO  \n"},
+		{"trailing literal", "%msg %name ", "hello world  \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := log.NewPatternEncoder(newTestEncoderConfig(), tt.pattern)
+			zap.String("uid", "10012").AddTo(enc)
+			buf, err := enc.EncodeEntry(newTestEntry(), []zapcore.Field{
+				zap.String("cmd", "log in"),
+				zap.Namespace("req"),
+				zap.Int("id", 7),
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestPatternEncoderMessageSpaces(t *testing.T) {
+	enc := log.NewPatternEncoder(newTestEncoderConfig(), "%level %msg")
+	ent := newTestEntry()
+	ent.Message = "indented \t "
+	buf, err := enc.EncodeEntry(ent, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "INFO indented \t \n", buf.String())
+}
+
+func TestPatternEncoderValues(t *testing.T) {
+	enc := log.NewPatternEncoder(newTestEncoderConfig(), "%fields")
+	clone := enc.Clone()
+	zap.Bool("ok", true).AddTo(clone)
+	buf, err := clone.EncodeEntry(newTestEntry(), []zapcore.Field{
+		zap.Ints("ids", []int{1, 2}),
+		zap.Strings("tags", []string{"a b", "c"}),
+		zap.Duration("cost", 1500*time.Millisecond),
+		zap.Error(errors.New("io timeout")),
+		zap.Any("obj", map[string]int{"a": 1}),
+		zap.Any("empty", struct{}{}),
+		zap.Any("nested", map[string]interface{}{"m": map[string]int{}}),
+		zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", "tom")
+			enc.AddInt("age", 3)
+			return nil
+		})),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `ok=true ids=[1,2] tags="[\"a b\",c]" cost=1.5s error="io timeout" obj.a=1 empty={} nested.m={} `+
+		"user.name=tom user.age=3\n", buf.String())
+
+	// the fields of an entry does not leak into the encoder.
+	buf, err = enc.EncodeEntry(newTestEntry(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "\n", buf.String())
+}
+
+func TestPatternFormatter(t *testing.T) {
+	stdout, _ := redirectStd(t, func() {
+		logger := log.NewZapLog([]log.OutputConfig{{
+			Writer:       "console",
+			Level:        "debug",
+			Formatter:    "pattern",
+			FormatConfig: log.FormatConfig{TimeFmt: "now", Pattern: "%time [%-5level] %msg %fields"},
+		}})
+		logger.With(log.Field{Key: "uid", Value: 1}).Infow("pattern formatter",
+			"req", map[string]interface{}{"path": "/login", "header": map[string]string{"ua": "curl"}})
+		assert.Nil(t, logger.Sync())
+	})
+	assert.Equal(t, "now [INFO ] pattern formatter uid=1 req.header.ua=curl req.path=/login\n", stdout)
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// watchConfig is the part of the yaml config file which holds the plugins.
+type watchConfig struct {
+	Plugins Config `yaml:"plugins"`
+}
+
+// Watch polls the yaml config file at path every interval. Whenever the content of the file
+// changes and can be parsed, onChange is called with the previous and the current configuration of
+// all plugins. The file must be readable when Watch is called. It returns a function which stops
+// watching.
+func Watch(path string, interval time.Duration, onChange func(prev, curr Config)) (func(), error) {
+	if interval <= 0 {
+		return nil, errors.New("watch interval must be positive")
+	}
+	data, prev, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				curData, curr, err := readConfig(path)
+				if err != nil || bytes.Equal(curData, data) {
+					// keep the previous configuration on a broken or half written file.
+					continue
+				}
+				onChange(prev, curr)
+				data, prev = curData, curr
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stop) })
+	}, nil
+}
+
+// readConfig reads and parses the plugins of the yaml config file at path.
+func readConfig(path string) ([]byte, Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := watchConfig{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, err
+	}
+	return data, cfg.Plugins, nil
+}
@@ -0,0 +1,39 @@
+package plugin_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperits/tlog/plugin"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugins.yaml")
+	_, err := plugin.Watch(path, time.Millisecond, func(prev, curr plugin.Config) {})
+	assert.NotNil(t, err)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("plugins:\n  mock_type:\n    mock_name: {}\n"), 0644))
+	_, err = plugin.Watch(path, 0, func(prev, curr plugin.Config) {})
+	assert.NotNil(t, err)
+
+	changed := make(chan [2]plugin.Config, 1)
+	stop, err := plugin.Watch(path, 10*time.Millisecond, func(prev, curr plugin.Config) {
+		changed <- [2]plugin.Config{prev, curr}
+	})
+	assert.Nil(t, err)
+	defer stop()
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("plugins:\n  mock_type:\n    mock_other: {}\n"), 0644))
+	select {
+	case c := <-changed:
+		assert.Contains(t, c[0]["mock_type"], "mock_name")
+		assert.Contains(t, c[1]["mock_type"], "mock_other")
+	case <-time.After(time.Second):
+		t.Fatal("config change not notified")
+	}
+	stop()
+}
@@ -0,0 +1,107 @@
+package log
+
+import "math"
+
+// protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// protoBuffer is a minimal protobuf encoder for the messages of the remote writers, which are
+// written field by field in the order of their numbers.
+type protoBuffer []byte
+
+func (b *protoBuffer) tag(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	*b = appendUvarint(*b, v)
+}
+
+// uint64Field writes a varint field, which is omitted if v is zero.
+func (b *protoBuffer) uint64Field(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireVarint)
+	b.varint(v)
+}
+
+// int64Field writes an int64 or int32 field, which is omitted if v is zero.
+func (b *protoBuffer) int64Field(field int, v int64) {
+	b.uint64Field(field, uint64(v))
+}
+
+// fixed64Field writes a fixed64 field, which is omitted if v is zero.
+func (b *protoBuffer) fixed64Field(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireFixed64)
+	for i := 0; i < 8; i++ {
+		*b = append(*b, byte(v>>(8*i)))
+	}
+}
+
+// oneofVarint writes a varint field of a oneof, which is written even if v is zero.
+func (b *protoBuffer) oneofVarint(field int, v uint64) {
+	b.tag(field, wireVarint)
+	b.varint(v)
+}
+
+// oneofDouble writes a double field of a oneof, which is written even if v is zero.
+func (b *protoBuffer) oneofDouble(field int, v float64) {
+	bits := math.Float64bits(v)
+	b.tag(field, wireFixed64)
+	for i := 0; i < 8; i++ {
+		*b = append(*b, byte(bits>>(8*i)))
+	}
+}
+
+// oneofString writes a string field of a oneof, which is written even if v is empty.
+func (b *protoBuffer) oneofString(field int, v string) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// bytesField writes a bytes field, which is omitted if v is empty.
+func (b *protoBuffer) bytesField(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// stringField writes a string field, which is omitted if v is empty.
+func (b *protoBuffer) stringField(field int, v string) {
+	if v == "" {
+		return
+	}
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// messageField writes an embedded message encoded by f. The message is written even if it is
+// empty.
+func (b *protoBuffer) messageField(field int, f func(m *protoBuffer)) {
+	var m protoBuffer
+	f(&m)
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(m)))
+	*b = append(*b, m...)
+}
+
+func appendUvarint(dst []byte, v uint64) []byte {
+	for v >= 0x80 {
+		dst = append(dst, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(dst, byte(v))
+}
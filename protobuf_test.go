@@ -0,0 +1,38 @@
+package log_test
+
+import "encoding/binary"
+
+// protoFields parses a protobuf message into the raw values of its fields by number. Varints are
+// kept as their encoded bytes, and fixed64 values as their 8 bytes.
+func protoFields(data []byte) map[int][][]byte {
+	fields := make(map[int][][]byte)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			_, n = binary.Uvarint(data)
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[:n])
+			data = data[n:]
+		case 1:
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[:8])
+			data = data[8:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[n:n+int(l)])
+			data = data[n+int(l):]
+		default:
+			return fields
+		}
+	}
+	return fields
+}
+
+// protoVarint decodes the first of the raw values of a varint field, or 0 if there is none.
+func protoVarint(values [][]byte) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	v, _ := binary.Uvarint(values[0])
+	return int64(v)
+}
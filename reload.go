@@ -0,0 +1,196 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap/zapcore"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// reloadMu serializes Reload, so that replaced writers are closed exactly once.
+var reloadMu sync.Mutex
+
+// Reload rebuilds the outputs of the registered Logger by name from a new Config, and atomically
+// swaps them into the Logger. Loggers handed out before, including the ones derived by With,
+// print to the new outputs afterwards. The writers of the replaced outputs are synced and closed,
+// after the logs being written to them finish.
+// The caller skip of the Logger is kept as it is.
+func Reload(name string, c Config) error {
+	if len(c) == 0 {
+		return errors.New("log config output empty")
+	}
+	l := toZapLog(Get(name))
+	if l == nil {
+		return fmt.Errorf("log: logger %s not exist or not reloadable", name)
+	}
+	cores, err := newZapCores(c)
+	if err != nil {
+		return err
+	}
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	old := l.cores.Load().(*zapCores)
+	l.cores.Store(cores)
+	// Wait for the logs being written to the old outputs before closing them.
+	old.mu.Lock()
+	old.retired = true
+	old.mu.Unlock()
+	_ = old.core.Sync()
+	return old.close()
+}
+
+// WatchConfig watches the yaml config file at path every interval, and reloads the registered
+// loggers whose configuration under plugins.log changes. Loggers which are not registered yet are
+// ignored. It returns a function which stops watching.
+func WatchConfig(path string, interval time.Duration) (func(), error) {
+	return plugin.Watch(path, interval, func(prev, curr plugin.Config) {
+		for name, node := range curr[pluginType] {
+			node := node
+			if prevNode, ok := prev[pluginType][name]; ok && sameNode(&prevNode, &node) {
+				continue
+			}
+			if Get(name) == nil {
+				continue
+			}
+			cfg := Config{}
+			if err := node.Decode(&cfg); err != nil {
+				Errorf("log: decode config of logger %s fail: %v", name, err)
+				continue
+			}
+			if err := Reload(name, cfg); err != nil {
+				Errorf("log: reload logger %s fail: %v", name, err)
+			}
+		}
+	})
+}
+
+// sameNode reports whether two yaml nodes hold the same content regardless of their positions.
+func sameNode(a, b *yaml.Node) bool {
+	ab, errA := yaml.Marshal(a)
+	bb, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ab, bb)
+}
+
+// zapCores is the set of outputs built from a Config. Reload replaces it as a whole.
+type zapCores struct {
+	core    zapcore.Core
+	outputs []zapOutput
+	closers []io.Closer
+	// memory registers the memory writers by the name and the index of their outputs.
+	memory map[string]*MemoryWriter
+
+	// mu is held for reading while logs are written to the outputs, and for writing by Reload to
+	// retire them, so that the outputs are only closed after the writes in flight finish.
+	mu      sync.RWMutex
+	retired bool
+}
+
+// acquireCores loads the current zapCores of v and holds it for writing until it is released.
+func acquireCores(v *atomic.Value) *zapCores {
+	for {
+		cores := v.Load().(*zapCores)
+		cores.mu.RLock()
+		if !cores.retired {
+			return cores
+		}
+		// Reload has swapped in new cores after the load.
+		cores.mu.RUnlock()
+	}
+}
+
+// release releases the zapCores held by acquireCores.
+func (c *zapCores) release() {
+	c.mu.RUnlock()
+}
+
+// close closes the writers of the outputs.
+func (c *zapCores) close() error {
+	var err error
+	for _, closer := range c.closers {
+		err = multierror.Append(err, closer.Close()).ErrorOrNil()
+	}
+	return err
+}
+
+// swapErrorOutput reports the errors of writing to the outputs, like the default ErrorOutput of
+// zap.Logger.
+var swapErrorOutput = zapcore.Lock(os.Stderr)
+
+// swapCore is a zapcore.Core which delegates to the current core of a zapLog, so that the
+// zap.Logger built on it follows Reload.
+type swapCore struct {
+	cores  *atomic.Value // *zapCores
+	fields []zapcore.Field
+
+	// derived caches the current core with fields added, which is rebuilt after Reload.
+	derived atomic.Value // *derivedCore
+}
+
+// derivedCore is the core derived from zapCores by adding fields.
+type derivedCore struct {
+	from *zapCores
+	core zapcore.Core
+}
+
+// current returns the core of cores with the fields added by With.
+func (c *swapCore) current(cores *zapCores) zapcore.Core {
+	if len(c.fields) == 0 {
+		return cores.core
+	}
+	if d, ok := c.derived.Load().(*derivedCore); ok && d.from == cores {
+		return d.core
+	}
+	d := &derivedCore{from: cores, core: cores.core.With(c.fields)}
+	c.derived.Store(d)
+	return d.core
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *swapCore) Enabled(lvl zapcore.Level) bool {
+	return c.current(c.cores.Load().(*zapCores)).Enabled(lvl)
+}
+
+// With implements zapcore.Core.
+func (c *swapCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return &swapCore{cores: c.cores, fields: all}
+}
+
+// Check implements zapcore.Core. The cores to write to are checked in Write, when the current
+// cores are held, as a Reload in between may close the outputs checked here.
+func (c *swapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *swapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	cores := acquireCores(c.cores)
+	defer cores.release()
+	if ce := c.current(cores).Check(ent, nil); ce != nil {
+		ce.ErrorOutput = swapErrorOutput
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *swapCore) Sync() error {
+	cores := acquireCores(c.cores)
+	defer cores.release()
+	return c.current(cores).Sync()
+}
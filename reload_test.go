@@ -0,0 +1,197 @@
+package log_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+	"github.com/hyperits/tlog/plugin"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type closeRecorder struct {
+	closed int
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed++
+	return nil
+}
+
+// reloadWriter is a writer which records logs by zap observer and reports when it is closed.
+type reloadWriter struct {
+	logs   *observer.ObservedLogs
+	closer *closeRecorder
+}
+
+func (f *reloadWriter) Type() string { return "log" }
+
+func (f *reloadWriter) Setup(name string, dec plugin.Decoder) error {
+	decoder, ok := dec.(*log.Decoder)
+	if !ok {
+		return errors.New("invalid decoder")
+	}
+	lvl := zap.NewAtomicLevelAt(log.Levels[decoder.OutputConfig.Level])
+	decoder.Core, f.logs = observer.New(lvl)
+	decoder.ZapLevel = lvl
+	decoder.Closer = f.closer
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	writerA := &reloadWriter{closer: &closeRecorder{}}
+	log.RegisterWriter("reload_a", writerA)
+	writerB := &reloadWriter{closer: &closeRecorder{}}
+	log.RegisterWriter("reload_b", writerB)
+
+	logger := log.NewZapLog([]log.OutputConfig{{Writer: "reload_a"}})
+	log.Register("reload", logger)
+	derived := logger.With(log.Field{Key: "uid", Value: "10012"})
+	derived.Info("before")
+	obA := writerA.logs
+	assert.Equal(t, 1, obA.Len())
+
+	assert.Nil(t, log.Reload("reload", []log.OutputConfig{{Name: "b", Writer: "reload_b", Level: "warn"}}))
+	assert.Equal(t, 1, writerA.closer.closed)
+	obB := writerB.logs
+
+	derived.Info("filtered")
+	derived.Warn("after")
+	logger.Error("plain")
+	assert.Equal(t, 1, obA.Len())
+	assert.Equal(t, 2, obB.Len())
+	assert.Equal(t, "after", obB.All()[0].Message)
+	assert.Equal(t, map[string]interface{}{"uid": "10012"}, obB.All()[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{}, obB.All()[1].ContextMap())
+	assert.Equal(t, log.LevelWarn, derived.GetLevel("b"))
+
+	assert.NotNil(t, log.Reload("reload", []log.OutputConfig{{Writer: "not_exist"}}))
+	assert.NotNil(t, log.Reload("reload", nil))
+	assert.NotNil(t, log.Reload("reload_not_exist", []log.OutputConfig{{Writer: "reload_b"}}))
+}
+
+// closingSink is a zapcore.WriteSyncer which counts the writes which end after it is closed.
+type closingSink struct {
+	closed      int32
+	afterClosed int32
+}
+
+func (s *closingSink) Write(p []byte) (int, error) {
+	time.Sleep(100 * time.Microsecond)
+	if atomic.LoadInt32(&s.closed) != 0 {
+		atomic.AddInt32(&s.afterClosed, 1)
+	}
+	return len(p), nil
+}
+
+func (s *closingSink) Sync() error { return nil }
+
+func (s *closingSink) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	return nil
+}
+
+// closingWriter is a writer whose outputs are closingSinks.
+type closingWriter struct {
+	mu    sync.Mutex
+	sinks []*closingSink
+}
+
+func (f *closingWriter) Type() string { return "log" }
+
+func (f *closingWriter) Setup(name string, dec plugin.Decoder) error {
+	decoder, ok := dec.(*log.Decoder)
+	if !ok {
+		return errors.New("invalid decoder")
+	}
+	sink := &closingSink{}
+	f.mu.Lock()
+	f.sinks = append(f.sinks, sink)
+	f.mu.Unlock()
+	lvl := zap.NewAtomicLevelAt(log.Levels[decoder.OutputConfig.Level])
+	decoder.Core = zapcore.NewCore(zapcore.NewJSONEncoder(log.NewEncoderConfig(decoder.OutputConfig)),
+		sink, lvl)
+	decoder.ZapLevel = lvl
+	decoder.Closer = sink
+	return nil
+}
+
+func TestReloadConcurrent(t *testing.T) {
+	w := &closingWriter{}
+	log.RegisterWriter("reload_concurrent", w)
+	logger := log.NewZapLog([]log.OutputConfig{{Writer: "reload_concurrent"}})
+	log.Register("reload_concurrent", logger)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			derived := logger.With(log.Field{Key: "goroutine", Value: i})
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					derived.Info("concurrent")
+					logger.Infow("concurrent", "goroutine", i)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 50; i++ {
+		time.Sleep(time.Millisecond)
+		assert.Nil(t, log.Reload("reload_concurrent", []log.OutputConfig{{Writer: "reload_concurrent"}}))
+	}
+	close(stop)
+	wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	assert.Len(t, w.sinks, 51)
+	for _, sink := range w.sinks {
+		assert.Equal(t, int32(0), atomic.LoadInt32(&sink.afterClosed))
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	const (
+		before = `
+plugins:
+  log:
+    watch:
+      - writer: console
+        level: info
+`
+		after = `
+plugins:
+  log:
+    watch:
+      - writer: console
+        level: error
+`
+	)
+	path := filepath.Join(t.TempDir(), "tlog.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(before), 0644))
+	logger := log.NewZapLog([]log.OutputConfig{{Writer: "console", Level: "info"}})
+	log.Register("watch", logger)
+
+	stop, err := log.WatchConfig(path, 10*time.Millisecond)
+	assert.Nil(t, err)
+	defer stop()
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(after), 0644))
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel("0") == log.LevelError
+	}, time.Second, 10*time.Millisecond)
+}
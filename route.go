@@ -0,0 +1,172 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// routeErrorOutput reports the errors of writing routed logs, like the default ErrorOutput of
+// zap.Logger.
+var routeErrorOutput = zapcore.Lock(os.Stderr)
+
+// router selects the logs of an output by the rules of its route config.
+type router struct {
+	include []routeRule
+	exclude []routeRule
+	// fields reports whether any rule matches fields, which are only known in Write.
+	fields bool
+}
+
+// routeRule is the compiled RouteRule.
+type routeRule struct {
+	fields  map[string]string
+	message *regexp.Regexp
+	logger  string
+}
+
+// newRouter compiles the route config. It returns nil if there are no rules.
+func newRouter(c *RouteConfig) (*router, error) {
+	if len(c.Include) == 0 && len(c.Exclude) == 0 {
+		return nil, nil
+	}
+	r := &router{}
+	var err error
+	if r.include, err = r.compile("include", c.Include); err != nil {
+		return nil, err
+	}
+	if r.exclude, err = r.compile("exclude", c.Exclude); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *router) compile(kind string, rules []RouteRule) ([]routeRule, error) {
+	compiled := make([]routeRule, len(rules))
+	for i, rule := range rules {
+		if len(rule.Fields) == 0 && rule.Message == "" && rule.Logger == "" {
+			return nil, errors.New(kind + " rule has no conditions")
+		}
+		compiled[i] = routeRule{fields: rule.Fields, logger: rule.Logger}
+		if rule.Message != "" {
+			re, err := regexp.Compile(rule.Message)
+			if err != nil {
+				return nil, errors.New(kind + " rule message: " + err.Error())
+			}
+			compiled[i].message = re
+		}
+		if len(rule.Fields) > 0 {
+			r.fields = true
+		}
+	}
+	return compiled, nil
+}
+
+// match reports whether the log is routed to the output. fields looks up the fields of the log,
+// and is nil if no rule matches fields.
+func (r *router) match(ent zapcore.Entry, fields func(key string) (interface{}, bool)) bool {
+	if len(r.include) > 0 && !anyRouteRule(r.include, ent, fields) {
+		return false
+	}
+	return !anyRouteRule(r.exclude, ent, fields)
+}
+
+func anyRouteRule(rules []routeRule, ent zapcore.Entry, fields func(string) (interface{}, bool)) bool {
+	for i := range rules {
+		if rules[i].match(ent, fields) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *routeRule) match(ent zapcore.Entry, fields func(string) (interface{}, bool)) bool {
+	if r.logger != "" && ent.LoggerName != r.logger && !strings.HasPrefix(ent.LoggerName, r.logger+".") {
+		return false
+	}
+	if r.message != nil && !r.message.MatchString(ent.Message) {
+		return false
+	}
+	for k, want := range r.fields {
+		v, ok := fields(k)
+		if !ok || want != "" && fieldValueString(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// routeCore is a zapcore.Core which only writes the logs routed to the output to its core. Rules
+// without fields are evaluated in Check, the others in Write before the log is encoded.
+type routeCore struct {
+	zapcore.Core
+	route *router
+	// with holds the fields added by With, only if the rules match fields.
+	with map[string]interface{}
+}
+
+// With implements zapcore.Core.
+func (c *routeCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if c.route.fields {
+		clone.with = c.withFields(fields).Fields
+	}
+	return &clone
+}
+
+// withFields returns a copy of the fields added by With, with more fields added.
+func (c *routeCore) withFields(fields []zapcore.Field) *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.with {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc
+}
+
+// Check implements zapcore.Core.
+func (c *routeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.route.fields {
+		if !c.route.match(ent, nil) {
+			return ce
+		}
+		return c.Core.Check(ent, ce)
+	}
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. The routed log is checked by the core again, so that the cores
+// teed in it, like the split console, keep their own levels.
+func (c *routeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var entry *zapcore.MapObjectEncoder
+	lookup := func(key string) (interface{}, bool) {
+		if entry == nil {
+			entry = zapcore.NewMapObjectEncoder()
+			for _, f := range fields {
+				f.AddTo(entry)
+			}
+		}
+		if v, ok := entry.Fields[key]; ok {
+			return v, true
+		}
+		v, ok := c.with[key]
+		return v, ok
+	}
+	if !c.route.match(ent, lookup) {
+		return nil
+	}
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.ErrorOutput = routeErrorOutput
+		ce.Write(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package log_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestRoute(t *testing.T) {
+	dir := t.TempDir()
+	var c log.Config
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+- writer: file
+  level: debug
+  formatter: pattern
+  formatter_config: {pattern: "%level %msg %fields"}
+  writer_config: {filename: `+filepath.Join(dir, "app.log")+`, write_mode: 1}
+  route:
+    exclude:
+      - fields: {category: audit}
+      - message: "^health"
+        logger: probe
+- writer: file
+  level: debug
+  formatter: pattern
+  formatter_config: {pattern: "%level %msg %fields"}
+  writer_config: {filename: `+filepath.Join(dir, "audit.log")+`, write_mode: 1}
+  route:
+    include:
+      - fields: {category: audit}
+- writer: file
+  level: debug
+  formatter: pattern
+  formatter_config: {pattern: "%level %msg"}
+  writer_config: {filename: `+filepath.Join(dir, "probe.log")+`, write_mode: 1}
+  route:
+    include:
+      - logger: probe
+`), &c))
+
+	logger := log.NewZapLog(c)
+	logger.Info("started")
+	logger.Infow("login", "category", "audit", "uid", 7)
+	audit := logger.With(log.Field{Key: "category", Value: "audit"})
+	audit.Warn("logout")
+	audit.Infow("overridden", "category", "app")
+	probe := log.Named(logger, "probe")
+	probe.Info("health ok")
+	log.Named(probe, "http").Info("health ok")
+	probe.Info("ready")
+	assert.Nil(t, logger.Sync())
+
+	read := func(filename string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		assert.Nil(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "INFO started\nINFO overridden category=audit category=app\nINFO ready\n", read("app.log"))
+	assert.Equal(t, "INFO login category=audit uid=7\nWARN logout category=audit\n", read("audit.log"))
+	assert.Equal(t, "INFO health ok\nINFO health ok\nINFO ready\n", read("probe.log"))
+}
+
+func TestRouteInvalid(t *testing.T) {
+	for _, r := range []log.RouteConfig{
+		{Include: []log.RouteRule{{}}},
+		{Exclude: []log.RouteRule{{Message: "("}}},
+	} {
+		assert.Panics(t, func() { log.NewZapLog([]log.OutputConfig{{Writer: "console", Route: r}}) })
+	}
+}
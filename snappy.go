@@ -0,0 +1,84 @@
+package log
+
+import "encoding/binary"
+
+// snappy block format, see https://github.com/google/snappy/blob/main/format_description.txt.
+const (
+	snappyBlockSize  = 1 << 16 // copies never refer beyond a block, so offsets fit in 2 bytes
+	snappyTableBits  = 14
+	snappyMinMatch   = 4
+	snappyTagLiteral = 0x00
+	snappyTagCopy1   = 0x01
+	snappyTagCopy2   = 0x02
+)
+
+// snappyEncode compresses src in the snappy block format, as required by the Loki push API.
+func snappyEncode(src []byte) []byte {
+	dst := make([]byte, 0, len(src)/2+16)
+	dst = appendUvarint(dst, uint64(len(src)))
+	for len(src) > 0 {
+		block := src
+		if len(block) > snappyBlockSize {
+			block = block[:snappyBlockSize]
+		}
+		src = src[len(block):]
+		dst = snappyEncodeBlock(dst, block)
+	}
+	return dst
+}
+
+// snappyEncodeBlock greedily replaces repeated sequences of 4 bytes by copies.
+func snappyEncodeBlock(dst, src []byte) []byte {
+	var table [1 << snappyTableBits]int32 // hash => position + 1
+	lit, s := 0, 0
+	for s+snappyMinMatch <= len(src) {
+		v := binary.LittleEndian.Uint32(src[s:])
+		h := (v * 0x1e35a7bd) >> (32 - snappyTableBits)
+		candidate := int(table[h]) - 1
+		table[h] = int32(s + 1)
+		if candidate < 0 || binary.LittleEndian.Uint32(src[candidate:]) != v {
+			s++
+			continue
+		}
+		dst = snappyEmitLiteral(dst, src[lit:s])
+		offset, length := s-candidate, snappyMinMatch
+		for s+length < len(src) && src[s+length] == src[candidate+length] {
+			length++
+		}
+		dst = snappyEmitCopy(dst, offset, length)
+		s += length
+		lit = s
+	}
+	return snappyEmitLiteral(dst, src[lit:])
+}
+
+func snappyEmitLiteral(dst, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+	n := len(lit) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|snappyTagLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|snappyTagLiteral, byte(n))
+	default:
+		dst = append(dst, 61<<2|snappyTagLiteral, byte(n), byte(n>>8))
+	}
+	return append(dst, lit...)
+}
+
+func snappyEmitCopy(dst []byte, offset, length int) []byte {
+	for length >= 68 {
+		dst = append(dst, 63<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+		length -= 64
+	}
+	if length > 64 {
+		dst = append(dst, 59<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+		length -= 60
+	}
+	if length >= 12 || offset >= 2048 {
+		return append(dst, byte(length-1)<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+	}
+	return append(dst, byte(offset>>8)<<5|byte(length-4)<<2|snappyTagCopy1, byte(offset))
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// staticFields expands the placeholders in the values of the static fields declared in config, and
+// returns them as zap fields sorted by key. Supported placeholders are ${hostname}, ${pid},
+// ${env:VAR} and ${env:VAR:-default}.
+func staticFields(fields map[string]string) ([]zap.Field, error) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	zapFields := make([]zap.Field, 0, len(keys))
+	for _, k := range keys {
+		v, err := expandPlaceholders(fields[k])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", k, err)
+		}
+		zapFields = append(zapFields, zap.String(k, v))
+	}
+	return zapFields, nil
+}
+
+// expandPlaceholders replaces the placeholders like ${hostname} in s.
+func expandPlaceholders(s string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("placeholder in %q not closed", s)
+		}
+		v, err := placeholderValue(s[start+2 : start+end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:start])
+		b.WriteString(v)
+		s = s[start+end+1:]
+	}
+}
+
+func placeholderValue(name string) (string, error) {
+	switch {
+	case name == "hostname":
+		return os.Hostname()
+	case name == "pid":
+		return strconv.Itoa(os.Getpid()), nil
+	case strings.HasPrefix(name, "env:"):
+		return envValue(strings.TrimPrefix(name, "env:"))
+	default:
+		return "", fmt.Errorf("placeholder ${%s} not supported", name)
+	}
+}
+
+// envValue returns the value of the environment variable in "VAR" or "VAR:-default". An unset
+// variable without default is an error, rather than silently expanding to empty.
+func envValue(name string) (string, error) {
+	var def string
+	i := strings.Index(name, ":-")
+	hasDefault := i >= 0
+	if hasDefault {
+		name, def = name[:i], name[i+2:]
+	}
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return "", fmt.Errorf("environment variable %s of ${env:%s} not set", name, name)
+}
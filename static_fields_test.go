@@ -0,0 +1,60 @@
+package log_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticFields(t *testing.T) {
+	os.Setenv("TLOG_TEST_VERSION", "v1.2.3")
+	defer os.Unsetenv("TLOG_TEST_VERSION")
+	hostname, err := os.Hostname()
+	assert.Nil(t, err)
+
+	stdout, _ := redirectStd(t, func() {
+		logger := log.NewZapLog([]log.OutputConfig{{
+			Writer:       "console",
+			Level:        "debug",
+			Formatter:    "pattern",
+			FormatConfig: log.FormatConfig{Pattern: "%msg %fields"},
+			Fields: map[string]string{
+				"service": "order",
+				"host":    "${hostname}",
+				"pid":     "${pid}",
+				"version": "${env:TLOG_TEST_VERSION}-${pid}",
+				"zone":    "${env:TLOG_TEST_UNSET:-local}",
+			},
+		}})
+		logger.With(log.Field{Key: "uid", Value: 1}).Info("hello")
+	})
+	pid := os.Getpid()
+	assert.Equal(t, fmt.Sprintf(
+		"hello host=%s pid=%d service=order version=v1.2.3-%d zone=local uid=1\n", hostname, pid, pid),
+		stdout)
+}
+
+func TestStaticFieldsInvalid(t *testing.T) {
+	assert.Panics(t, func() {
+		log.NewZapLog([]log.OutputConfig{{
+			Writer: "console",
+			Fields: map[string]string{"a": "${unknown}"},
+		}})
+	})
+	assert.Panics(t, func() {
+		log.NewZapLog([]log.OutputConfig{{
+			Writer: "console",
+			Fields: map[string]string{"a": "${pid"},
+		}})
+	})
+	assert.Panics(t, func() {
+		log.NewZapLog([]log.OutputConfig{{
+			Writer: "console",
+			Fields: map[string]string{"a": "${env:TLOG_TEST_UNSET}"},
+		}})
+	})
+}
@@ -0,0 +1,305 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperits/tlog/plugin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// syslog message formats.
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+)
+
+// syslogSockets are the local syslog sockets tried when no address is configured.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogFacilities maps facility names to syslog facility codes.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverity maps zap levels to syslog severities.
+func syslogSeverity(l zapcore.Level) int {
+	switch {
+	case l >= zapcore.DPanicLevel:
+		return 2 // critical
+	case l == zapcore.ErrorLevel:
+		return 3 // error
+	case l == zapcore.WarnLevel:
+		return 4 // warning
+	case l == zapcore.InfoLevel:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// SyslogConfig is the config of the syslog writer, which is set in remote_config.
+type SyslogConfig struct {
+	// Network is the network to the syslog server, one of unix, unixgram, udp and tcp. If both
+	// Network and Address are empty, the local syslog socket is used.
+	Network string `yaml:"network"`
+	// Address is the address of the syslog server, like "127.0.0.1:514" or "/dev/log".
+	Address string `yaml:"address"`
+	// Facility is the syslog facility like user, daemon or local0, default as user.
+	Facility string `yaml:"facility"`
+	// AppName is the application name in messages, default as the name of the executable.
+	AppName string `yaml:"app_name"`
+	// Format is the message format, rfc5424 or rfc3164, default as rfc5424.
+	Format string `yaml:"format"`
+	// Timeout is the timeout in milliseconds to connect and to write, default as 5000.
+	Timeout int `yaml:"timeout"`
+	// MinBackoff and MaxBackoff are the bounds in milliseconds of the exponential backoff between
+	// reconnections, default as 100 and 30000. Logs are dropped while waiting for the backoff.
+	MinBackoff int `yaml:"min_backoff"`
+	MaxBackoff int `yaml:"max_backoff"`
+}
+
+// SyslogWriterFactory is the syslog writer instance Factory.
+type SyslogWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *SyslogWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers syslog output writer.
+func (f *SyslogWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("syslog writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("syslog writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	sc := &SyslogConfig{}
+	if err := decodeRemoteConfig(cfg, sc); err != nil {
+		return fmt.Errorf("syslog writer remote config invalid: %v", err)
+	}
+	w, err := newSyslogWriter(sc)
+	if err != nil {
+		return err
+	}
+	lvl := zap.NewAtomicLevelAt(Levels[cfg.Level])
+	decoder.Core = &syslogCore{LevelEnabler: lvl, enc: newEncoder(cfg), w: w}
+	decoder.ZapLevel, decoder.Closer = lvl, w
+	return nil
+}
+
+// syslogWriter writes syslog messages to a connection, which is reestablished on errors.
+type syslogWriter struct {
+	network  string
+	address  string
+	priority int // facility * 8
+	appName  string
+	hostname string
+	pid      int
+	rfc3164  bool
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	stream  bool // whether conn is a stream connection, whose messages end with newlines
+	backoff reconnectBackoff
+}
+
+func newSyslogWriter(c *SyslogConfig) (*syslogWriter, error) {
+	if c.Network != "" && c.Address == "" {
+		return nil, errors.New("syslog writer address empty")
+	}
+	facility, ok := syslogFacilities[GetLogEncoderKey("user", c.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("syslog writer facility %s invalid", c.Facility)
+	}
+	format := GetLogEncoderKey(SyslogRFC5424, c.Format)
+	if format != SyslogRFC5424 && format != SyslogRFC3164 {
+		return nil, fmt.Errorf("syslog writer format %s invalid", c.Format)
+	}
+	appName := c.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+	return &syslogWriter{
+		network:  c.Network,
+		address:  c.Address,
+		priority: facility * 8,
+		appName:  appName,
+		hostname: hostname,
+		pid:      os.Getpid(),
+		rfc3164:  format == SyslogRFC3164,
+		timeout:  msOrDefault(c.Timeout, 5000),
+		backoff:  newReconnectBackoff(c.MinBackoff, c.MaxBackoff),
+	}, nil
+}
+
+// write sends a message with the severity. A broken connection is reestablished at once, and the
+// later failures to connect are backed off, so that a dead server does not stall each log.
+func (w *syslogWriter) write(severity int, t time.Time, msg string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		if err := w.send(severity, t, msg); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return err
+	}
+	if err := w.send(severity, t, msg); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		w.backoff.fail()
+		return err
+	}
+	return nil
+}
+
+// send formats a message and writes it to the connection.
+func (w *syslogWriter) send(severity int, t time.Time, msg string) error {
+	_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	_, err := w.conn.Write(w.format(severity, t, msg))
+	return err
+}
+
+// format formats a message. Messages over stream connections are terminated by a newline.
+func (w *syslogWriter) format(severity int, t time.Time, msg string) []byte {
+	var s string
+	if w.rfc3164 {
+		s = fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+			w.priority+severity, t.Format(time.Stamp), w.hostname, w.appName, w.pid, msg)
+	} else {
+		s = fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+			w.priority+severity, t.Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, w.appName, w.pid, msg)
+	}
+	if w.stream && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return []byte(s)
+}
+
+// connect dials the syslog server, or the local syslog socket if no address is configured, unless
+// it is waiting for the backoff.
+func (w *syslogWriter) connect() error {
+	if err := w.backoff.wait(); err != nil {
+		return err
+	}
+	if w.address != "" {
+		conn, err := net.DialTimeout(w.network, w.address, w.timeout)
+		if err != nil {
+			w.backoff.fail()
+			return err
+		}
+		w.setConn(conn, w.network)
+		return nil
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogSockets {
+			if conn, err := net.DialTimeout(network, path, w.timeout); err == nil {
+				w.setConn(conn, network)
+				return nil
+			}
+		}
+	}
+	w.backoff.fail()
+	return errors.New("syslog writer local syslog socket unavailable")
+}
+
+func (w *syslogWriter) setConn(conn net.Conn, network string) {
+	w.conn = conn
+	w.stream = network == "tcp" || network == "tcp4" || network == "tcp6" || network == "unix"
+	w.backoff.reset()
+}
+
+// Close closes the connection.
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// syslogCore is a zapcore.Core which sends each entry as a syslog message whose severity follows
+// the level of the entry.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *syslogWriter
+}
+
+// With implements zapcore.Core.
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w}
+}
+
+// Check implements zapcore.Core.
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := strings.TrimRight(buf.String(), "\r\n")
+	buf.Free()
+	return c.w.write(syslogSeverity(ent.Level), ent.Time, msg)
+}
+
+// Sync implements zapcore.Core.
+func (c *syslogCore) Sync() error {
+	return nil
+}
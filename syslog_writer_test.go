@@ -0,0 +1,105 @@
+package log_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSyslogLogger creates a Logger with a syslog output configured by remote_config.
+func newSyslogLogger(t *testing.T, remote string) log.Logger {
+	return newWriterLogger(t, `{writer: syslog, formatter: pattern, formatter_config: {pattern: "%msg %fields"}}`,
+		remote)
+}
+
+func TestSyslogWriterUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	logger := newSyslogLogger(t, fmt.Sprintf(
+		"{network: unixgram, address: %s, facility: local0, app_name: app}", path))
+	logger.Infow("hello", "uid", 1)
+	logger.Error("oops")
+
+	hostname, _ := os.Hostname()
+	buf := make([]byte, 1024)
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	assert.Regexp(t, fmt.Sprintf(`^<134>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}\S+ %s app %d - - hello uid=1$`,
+		hostname, os.Getpid()), string(buf[:n]))
+	n, err = conn.Read(buf)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^<131>1 .* - - oops$`, string(buf[:n]))
+}
+
+func TestSyslogWriterRFC3164Reconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	ln, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	logger := newSyslogLogger(t, fmt.Sprintf(
+		"{network: unix, address: %s, facility: daemon, app_name: app, format: rfc3164}", path))
+	logger.Warn("first")
+
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Regexp(t, fmt.Sprintf(`^<28>\w{3} [ \d]\d \d\d:\d\d:\d\d \S+ app\[%d\]: first\n$`, os.Getpid()), line)
+
+	// The server drops the connection, and the writer connects again.
+	assert.Nil(t, conn.Close())
+	logger.Debug("second")
+	conn, err = ln.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+	line, err = bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Regexp(t, `^<31>.* app\[\d+\]: second\n$`, line)
+}
+
+func TestSyslogWriterBackoff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	logger := newSyslogLogger(t, fmt.Sprintf(
+		"{network: unix, address: %s, min_backoff: 200, max_backoff: 200}", path))
+	logger.Info("no server")
+
+	ln, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	defer ln.Close()
+	// The writer does not connect again until the backoff passes, so the log is dropped.
+	start := time.Now()
+	logger.Info("backoff")
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+
+	time.Sleep(250 * time.Millisecond)
+	logger.Info("connected")
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Regexp(t, ` - - connected\n$`, line)
+}
+
+func TestSyslogWriterInvalidConfig(t *testing.T) {
+	for _, remote := range []string{
+		"{network: udp}",
+		"{network: udp, address: 127.0.0.1:514, facility: unknown}",
+		"{network: udp, address: 127.0.0.1:514, format: rfc1}",
+	} {
+		assert.Panics(t, func() { newSyslogLogger(t, remote) }, remote)
+	}
+}
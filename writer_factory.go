@@ -12,6 +12,28 @@ var (
 	DefaultConsoleWriterFactory = &ConsoleWriterFactory{}
 	// DefaultFileWriterFactory is the default file output implementation.
 	DefaultFileWriterFactory = &FileWriterFactory{}
+	// DefaultStderrWriterFactory is the default stderr output implementation.
+	DefaultStderrWriterFactory = &StderrWriterFactory{}
+	// DefaultIOWriterFactory is the default io.Writer output implementation.
+	DefaultIOWriterFactory = &IOWriterFactory{}
+	// DefaultSyslogWriterFactory is the default syslog output implementation.
+	DefaultSyslogWriterFactory = &SyslogWriterFactory{}
+	// DefaultNetWriterFactory is the default tcp/udp output implementation.
+	DefaultNetWriterFactory = &NetWriterFactory{}
+	// DefaultHTTPWriterFactory is the default http output implementation.
+	DefaultHTTPWriterFactory = &HTTPWriterFactory{}
+	// DefaultLokiWriterFactory is the default loki output implementation.
+	DefaultLokiWriterFactory = &LokiWriterFactory{}
+	// DefaultElasticsearchWriterFactory is the default elasticsearch output implementation.
+	DefaultElasticsearchWriterFactory = &ElasticsearchWriterFactory{}
+	// DefaultFluentdWriterFactory is the default fluentd output implementation.
+	DefaultFluentdWriterFactory = &FluentdWriterFactory{}
+	// DefaultOTLPWriterFactory is the default OTLP/HTTP output implementation.
+	DefaultOTLPWriterFactory = &OTLPWriterFactory{}
+	// DefaultJournaldWriterFactory is the default journald output implementation.
+	DefaultJournaldWriterFactory = &JournaldWriterFactory{}
+	// DefaultMemoryWriterFactory is the default in-memory output implementation.
+	DefaultMemoryWriterFactory = &MemoryWriterFactory{}
 
 	writers = make(map[string]plugin.Factory)
 )
@@ -48,10 +70,71 @@ func (f *ConsoleWriterFactory) Setup(name string, dec plugin.Decoder) error {
 	if err := decoder.Decode(&cfg); err != nil {
 		return err
 	}
+	if lvl := cfg.WriteConfig.StderrLevel; lvl != "" {
+		if _, ok := Levels[lvl]; !ok {
+			return errors.New("console writer stderr level " + lvl + " invalid")
+		}
+	}
 	decoder.Core, decoder.ZapLevel = newConsoleCore(cfg)
 	return nil
 }
 
+// StderrWriterFactory is the stderr writer instance.
+type StderrWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *StderrWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers stderr output writer.
+func (f *StderrWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("stderr writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("stderr writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	decoder.Core, decoder.ZapLevel = newStderrCore(cfg)
+	return nil
+}
+
+// IOWriterFactory is the io.Writer writer instance.
+type IOWriterFactory struct {
+}
+
+// Type returns the log plugin type.
+func (f *IOWriterFactory) Type() string {
+	return pluginType
+}
+
+// Setup starts, loads and registers io.Writer output writer, which writes to
+// WriteConfig.Writer.
+func (f *IOWriterFactory) Setup(name string, dec plugin.Decoder) error {
+	if dec == nil {
+		return errors.New("io writer decoder empty")
+	}
+	decoder, ok := dec.(*Decoder)
+	if !ok {
+		return errors.New("io writer log decoder type invalid")
+	}
+	cfg := &OutputConfig{}
+	if err := decoder.Decode(&cfg); err != nil {
+		return err
+	}
+	if cfg.WriteConfig.Writer == nil {
+		return errors.New("io writer destination empty, WriteConfig.Writer must be set")
+	}
+	decoder.Core, decoder.ZapLevel = newWriterCore(cfg)
+	return nil
+}
+
 // FileWriterFactory is the file writer instance Factory.
 type FileWriterFactory struct {
 }
@@ -92,10 +175,19 @@ func (f *FileWriterFactory) setupConfig(decoder *Decoder) error {
 		// It has better performance, discards logs on full and avoid blocking service.
 		cfg.WriteConfig.WriteMode = WriteFast
 	}
-	core, level, err := newFileCore(cfg)
+	core, level, closer, err := newFileCore(cfg)
 	if err != nil {
 		return err
 	}
-	decoder.Core, decoder.ZapLevel = core, level
+	decoder.Core, decoder.ZapLevel, decoder.Closer = core, level, closer
 	return nil
 }
+
+// decodeRemoteConfig decodes the remote_config of c into v. v is kept as it is if remote_config is
+// absent.
+func decodeRemoteConfig(c *OutputConfig, v interface{}) error {
+	if c.RemoteConfig.Kind == 0 {
+		return nil
+	}
+	return c.RemoteConfig.Decode(v)
+}
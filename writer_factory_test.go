@@ -0,0 +1,124 @@
+package log_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/hyperits/tlog"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// newWriterLogger creates a Logger whose last output is configured by the yaml of the output
+// config and of its remote_config, at debug level unless configured. The outputs are preceded by
+// the given ones. The Logger is closed when the test finishes, so that the connections and
+// goroutines of its writers do not leak into later tests.
+func newWriterLogger(t *testing.T, output, remote string, outputs ...log.OutputConfig) log.Logger {
+	cfg := log.OutputConfig{Level: "debug"}
+	assert.Nil(t, yaml.Unmarshal([]byte(output), &cfg))
+	assert.Nil(t, yaml.Unmarshal([]byte(remote), &cfg.RemoteConfig))
+	logger := log.NewZapLog(append(outputs, cfg))
+	t.Cleanup(func() { _ = log.CloseLogger(logger) })
+	return logger
+}
+
+// redirectStd redirects stdout and stderr to files while f creates loggers and prints logs.
+func redirectStd(t *testing.T, f func()) (string, string) {
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	assert.Nil(t, err)
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	assert.Nil(t, err)
+
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	f()
+	os.Stdout, os.Stderr = oldStdout, oldStderr
+	assert.Nil(t, stdout.Close())
+	assert.Nil(t, stderr.Close())
+
+	out, err := ioutil.ReadFile(stdout.Name())
+	assert.Nil(t, err)
+	errOut, err := ioutil.ReadFile(stderr.Name())
+	assert.Nil(t, err)
+	return string(out), string(errOut)
+}
+
+func TestStderrWriter(t *testing.T) {
+	stdout, stderr := redirectStd(t, func() {
+		logger := log.NewZapLog([]log.OutputConfig{{
+			Writer:       "stderr",
+			Level:        "info",
+			Formatter:    "pattern",
+			FormatConfig: log.FormatConfig{Pattern: "%level %msg"},
+		}})
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Error("error")
+	})
+	assert.Equal(t, "", stdout)
+	assert.Equal(t, "INFO info\nERROR error\n", stderr)
+}
+
+func TestConsoleWriterSplitStream(t *testing.T) {
+	stdout, stderr := redirectStd(t, func() {
+		logger := log.NewZapLog([]log.OutputConfig{{
+			Writer:       "console",
+			Level:        "debug",
+			Formatter:    "pattern",
+			FormatConfig: log.FormatConfig{Pattern: "%level %msg"},
+			WriteConfig:  log.WriteConfig{StderrLevel: "warn"},
+		}})
+		logger.Trace("trace")
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error("error")
+
+		logger.SetLevel("0", log.LevelError)
+		logger.Info("info filtered")
+		logger.Warn("warn filtered")
+	})
+	assert.Equal(t, "DEBUG debug\nINFO info\n", stdout)
+	assert.Equal(t, "WARN warn\nERROR error\n", stderr)
+
+	assert.Panics(t, func() {
+		log.NewZapLog([]log.OutputConfig{{Writer: "console", WriteConfig: log.WriteConfig{StderrLevel: "x"}}})
+	})
+}
+
+// syncBuffer is a zapcore.WriteSyncer which counts its Sync calls.
+type syncBuffer struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (b *syncBuffer) Sync() error {
+	b.syncs++
+	return nil
+}
+
+func TestIOWriter(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := log.NewZapLog([]log.OutputConfig{{
+		Writer:       "writer",
+		Level:        "info",
+		Formatter:    "pattern",
+		FormatConfig: log.FormatConfig{Pattern: "%level %msg"},
+		WriteConfig:  log.WriteConfig{Writer: buf},
+	}})
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Error("error")
+	assert.Nil(t, logger.Sync())
+	assert.Equal(t, "INFO info\nERROR error\n", buf.String())
+	assert.Equal(t, 1, buf.syncs)
+
+	assert.Panics(t, func() {
+		log.NewZapLog([]log.OutputConfig{{Writer: "writer"}})
+	})
+}
@@ -1,10 +1,14 @@
 package log
 
 import (
+	"errors"
 	"fmt"
 	"github.com/hyperits/tlog/rollwriter"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 
@@ -26,9 +30,13 @@ const (
 	FileZapCore    = "file"
 )
 
+// ZapTraceLevel is the zapcore.Level of TRACE log, one level below zapcore.DebugLevel.
+const ZapTraceLevel = zapcore.DebugLevel - 1
+
 // Levels is the map from string to zapcore.Level.
 var Levels = map[string]zapcore.Level{
 	"":      zapcore.DebugLevel,
+	"trace": ZapTraceLevel,
 	"debug": zapcore.DebugLevel,
 	"info":  zapcore.InfoLevel,
 	"warn":  zapcore.WarnLevel,
@@ -37,7 +45,7 @@ var Levels = map[string]zapcore.Level{
 }
 
 var levelToZapLevel = map[Level]zapcore.Level{
-	LevelTrace: zapcore.DebugLevel,
+	LevelTrace: ZapTraceLevel,
 	LevelDebug: zapcore.DebugLevel,
 	LevelInfo:  zapcore.InfoLevel,
 	LevelWarn:  zapcore.WarnLevel,
@@ -46,6 +54,7 @@ var levelToZapLevel = map[Level]zapcore.Level{
 }
 
 var zapLevelToLevel = map[zapcore.Level]Level{
+	ZapTraceLevel:      LevelTrace,
 	zapcore.DebugLevel: LevelDebug,
 	zapcore.InfoLevel:  LevelInfo,
 	zapcore.WarnLevel:  LevelWarn,
@@ -60,34 +69,139 @@ func NewZapLog(c Config) Logger {
 
 // NewZapLogWithCallerSkip creates a tlog default Logger from zap.
 func NewZapLogWithCallerSkip(c Config, callerSkip int) Logger {
-	var (
-		cores  []zapcore.Core
-		levels []zap.AtomicLevel
-	)
-	for _, o := range c {
-		writer := GetWriter(o.Writer)
-		if writer == nil {
-			panic("log: writer core: " + o.Writer + " no registered")
-		}
-		decoder := &Decoder{OutputConfig: &o}
-		if err := writer.Setup(o.Writer, decoder); err != nil {
-			panic("log: writer core: " + o.Writer + " setup fail: " + err.Error())
-		}
-		cores = append(cores, decoder.Core)
-		levels = append(levels, decoder.ZapLevel)
+	cores, err := newZapCores(c)
+	if err != nil {
+		panic(err.Error())
 	}
+	v := &atomic.Value{}
+	v.Store(cores)
 	return &zapLog{
-		levels: levels,
+		cores: v,
 		logger: zap.New(
-			zapcore.NewTee(cores...),
+			&swapCore{cores: v},
 			zap.AddCallerSkip(callerSkip),
 			zap.AddCaller(),
 		),
 	}
 }
 
+// NewLogger creates a Logger with a single output configured by options. Logs are written to the
+// writer of the options, or to the console if it is not set. It panics if the options are invalid,
+// like NewZapLog.
+func NewLogger(opts ...LoggerOption) Logger {
+	o := &LoggerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	c := OutputConfig{
+		Writer:    OutputConsole,
+		Level:     LevelStrings[o.LogLevel],
+		Formatter: o.Formatter,
+	}
+	if o.Writer != nil {
+		c.Writer, c.WriteConfig.Writer = OutputWriter, o.Writer
+	}
+	if o.Pattern != "" {
+		c.FormatConfig.Pattern = o.Pattern
+		if c.Formatter == "" {
+			c.Formatter = FormatterPattern
+		}
+	}
+	return NewZapLog(Config{c})
+}
+
+// newZapCores sets up the writer of each output and tees their cores.
+func newZapCores(c Config) (*zapCores, error) {
+	cores := &zapCores{}
+	var tee []zapcore.Core
+	for i, o := range c {
+		if o.Name != "" {
+			for _, out := range cores.outputs {
+				if out.name == o.Name {
+					cores.close()
+					return nil, errors.New("log: output name: " + o.Name + " duplicated")
+				}
+			}
+		}
+		if _, err := strconv.Atoi(o.Name); err == nil && o.Name != strconv.Itoa(i) {
+			cores.close()
+			return nil, errors.New("log: output name: " + o.Name + " conflicts with output index")
+		}
+		if o.Formatter != "" && GetFormatter(o.Formatter) == nil {
+			cores.close()
+			return nil, errors.New("log: formatter: " + o.Formatter + " no registered")
+		}
+		if err := validateFormatConfig(&o.FormatConfig); err != nil {
+			cores.close()
+			return nil, errors.New("log: formatter config invalid: " + err.Error())
+		}
+		if err := validateLevelRange(&o); err != nil {
+			cores.close()
+			return nil, errors.New("log: level range invalid: " + err.Error())
+		}
+		route, err := newRouter(&o.Route)
+		if err != nil {
+			cores.close()
+			return nil, errors.New("log: route invalid: " + err.Error())
+		}
+		writer := GetWriter(o.Writer)
+		if writer == nil {
+			cores.close()
+			return nil, errors.New("log: writer core: " + o.Writer + " no registered")
+		}
+		decoder := &Decoder{OutputConfig: &o}
+		if err := writer.Setup(o.Writer, decoder); err != nil {
+			cores.close()
+			return nil, errors.New("log: writer core: " + o.Writer + " setup fail: " + err.Error())
+		}
+		if len(o.Fields) > 0 {
+			fields, err := staticFields(o.Fields)
+			if err != nil {
+				cores.close()
+				return nil, errors.New("log: writer core: " + o.Writer + " fields invalid: " + err.Error())
+			}
+			decoder.Core = decoder.Core.With(fields)
+		}
+		decoder.Core = newLevelRangeCore(&o, decoder.Core)
+		if route != nil {
+			decoder.Core = &routeCore{Core: decoder.Core, route: route}
+		}
+		tee = append(tee, decoder.Core)
+		cores.outputs = append(cores.outputs, zapOutput{
+			name:   o.Name,
+			writer: o.Writer,
+			level:  decoder.ZapLevel,
+		})
+		if decoder.Closer != nil {
+			cores.closers = append(cores.closers, decoder.Closer)
+		}
+		if decoder.memory != nil {
+			if cores.memory == nil {
+				cores.memory = make(map[string]*MemoryWriter)
+			}
+			cores.memory[strconv.Itoa(i)] = decoder.memory
+			if o.Name != "" {
+				cores.memory[o.Name] = decoder.memory
+			}
+		}
+	}
+	cores.core = zapcore.NewTee(tee...)
+	return cores, nil
+}
+
 func newEncoder(c *OutputConfig) zapcore.Encoder {
-	encoderCfg := zapcore.EncoderConfig{
+	formatter := GetFormatter(GetLogEncoderKey(FormatterConsole, c.Formatter))
+	if formatter == nil {
+		// Unknown formatters are rejected before writers are set up, see newZapCores.
+		formatter = GetFormatter(FormatterConsole)
+	}
+	return formatter(c)
+}
+
+// NewEncoderConfig creates the zapcore.EncoderConfig of an output from its FormatConfig. Custom
+// formatters may use it to honor the format config.
+func NewEncoderConfig(c *OutputConfig) zapcore.EncoderConfig {
+	cfg := zapcore.EncoderConfig{
 		TimeKey:        GetLogEncoderKey("T", c.FormatConfig.TimeKey),
 		LevelKey:       GetLogEncoderKey("L", c.FormatConfig.LevelKey),
 		NameKey:        GetLogEncoderKey("N", c.FormatConfig.NameKey),
@@ -96,19 +210,20 @@ func newEncoder(c *OutputConfig) zapcore.Encoder {
 		MessageKey:     GetLogEncoderKey("M", c.FormatConfig.MessageKey),
 		StacktraceKey:  GetLogEncoderKey("S", c.FormatConfig.StacktraceKey),
 		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.CapitalLevelEncoder,
-		EncodeTime:     NewTimeEncoder(c.FormatConfig.TimeFmt),
-		EncodeDuration: zapcore.StringDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
-	switch c.Formatter {
-	case "console":
-		return zapcore.NewConsoleEncoder(encoderCfg)
-	case "json":
-		return zapcore.NewJSONEncoder(encoderCfg)
-	default:
-		return zapcore.NewConsoleEncoder(encoderCfg)
+		EncodeLevel:    levelEncoder(c),
+		EncodeTime:     NewTimeEncoderInLocation(c.FormatConfig.TimeFmt, timeZone(c)),
+		EncodeDuration: durationEncoders[c.FormatConfig.DurationEncoder],
+		EncodeCaller:   callerEncoders[c.FormatConfig.CallerEncoder],
+	}
+	if colorEnabled(c) {
+		if c.FormatConfig.DimTime {
+			cfg.EncodeTime = dimTimeEncoder(cfg.EncodeTime)
+		}
+		if c.FormatConfig.HighlightCaller {
+			cfg.EncodeCaller = highlightCallerEncoder(cfg.EncodeCaller)
+		}
 	}
+	return cfg
 }
 
 // GetLogEncoderKey gets user defined log output name, uses defKey if empty.
@@ -120,14 +235,52 @@ func GetLogEncoderKey(defKey, key string) string {
 }
 
 func newConsoleCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel) {
+	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
+	if c.WriteConfig.StderrLevel == "" {
+		return zapcore.NewCore(
+			newEncoder(c),
+			zapcore.Lock(os.Stdout),
+			lvl), lvl
+	}
+
+	// split streams by level. The stderr stream has its own encoder, whose auto color is decided
+	// by whether stderr is a terminal.
+	split := Levels[c.WriteConfig.StderrLevel]
+	stderr := *c
+	stderr.Writer = OutputStderr
+	return zapcore.NewTee(
+		zapcore.NewCore(
+			newEncoder(c),
+			zapcore.Lock(os.Stdout),
+			zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+				return lvl.Enabled(l) && l < split
+			})),
+		zapcore.NewCore(
+			newEncoder(&stderr),
+			zapcore.Lock(os.Stderr),
+			zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+				return lvl.Enabled(l) && l >= split
+			})),
+	), lvl
+}
+
+func newStderrCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel) {
+	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
+	return zapcore.NewCore(
+		newEncoder(c),
+		zapcore.Lock(os.Stderr),
+		lvl), lvl
+}
+
+func newWriterCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel) {
 	lvl := zap.NewAtomicLevelAt(Levels[c.Level])
 	return zapcore.NewCore(
 		newEncoder(c),
-		zapcore.Lock(os.Stdout),
+		zapcore.Lock(zapcore.AddSync(c.WriteConfig.Writer)),
 		lvl), lvl
 }
 
-func newFileCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, error) {
+func newFileCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, io.Closer, error) {
 	opts := []rollwriter.Option{
 		rollwriter.WithMaxAge(c.WriteConfig.MaxAge),
 		rollwriter.WithMaxBackups(c.WriteConfig.MaxBackups),
@@ -140,18 +293,22 @@ func newFileCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, error) {
 	}
 	writer, err := rollwriter.NewRollWriter(c.WriteConfig.Filename, opts...)
 	if err != nil {
-		return nil, zap.AtomicLevel{}, err
+		return nil, zap.AtomicLevel{}, nil, err
 	}
 
 	// write mod.
-	var ws zapcore.WriteSyncer
+	var (
+		ws     zapcore.WriteSyncer
+		closer io.Closer = writer
+	)
 	if c.WriteConfig.WriteMode == WriteSync {
 		ws = zapcore.AddSync(writer)
 	} else {
 		dropLog := c.WriteConfig.WriteMode == WriteFast
-		ws = rollwriter.NewAsyncRollWriter(writer,
+		asyncWriter := rollwriter.NewAsyncRollWriter(writer,
 			rollwriter.WithDropLog(dropLog),
 		)
+		ws, closer = asyncWriter, asyncWriter
 	}
 
 	// log level.
@@ -159,15 +316,39 @@ func newFileCore(c *OutputConfig) (zapcore.Core, zap.AtomicLevel, error) {
 	return zapcore.NewCore(
 		newEncoder(c),
 		ws, lvl,
-	), lvl, nil
+	), lvl, closer, nil
 }
 
-// NewTimeEncoder creates a time format encoder.
+// CapitalLevelEncoder serializes a Level to an all-caps string, such as "TRACE" or "INFO".
+func CapitalLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if l == ZapTraceLevel {
+		enc.AppendString("TRACE")
+		return
+	}
+	zapcore.CapitalLevelEncoder(l, enc)
+}
+
+// LowercaseLevelEncoder serializes a Level to a lowercase string, such as "trace" or "info".
+func LowercaseLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if l == ZapTraceLevel {
+		enc.AppendString("trace")
+		return
+	}
+	zapcore.LowercaseLevelEncoder(l, enc)
+}
+
+// NewTimeEncoder creates a time format encoder in the local time zone.
 func NewTimeEncoder(format string) zapcore.TimeEncoder {
+	return NewTimeEncoderInLocation(format, time.Local)
+}
+
+// NewTimeEncoderInLocation creates a time format encoder which formats time in loc. Epoch
+// timestamps are not affected by loc.
+func NewTimeEncoderInLocation(format string, loc *time.Location) zapcore.TimeEncoder {
 	switch format {
 	case "":
 		return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-			enc.AppendByteString(DefaultTimeFormat(t))
+			enc.AppendByteString(formatDefaultTime(t.In(loc)))
 		}
 	case "seconds":
 		return zapcore.EpochTimeEncoder
@@ -177,7 +358,7 @@ func NewTimeEncoder(format string) zapcore.TimeEncoder {
 		return zapcore.EpochNanosTimeEncoder
 	default:
 		return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-			enc.AppendString(CustomTimeFormat(t, format))
+			enc.AppendString(CustomTimeFormat(t.In(loc), format))
 		}
 	}
 }
@@ -187,9 +368,13 @@ func CustomTimeFormat(t time.Time, format string) string {
 	return t.Format(format)
 }
 
-// DefaultTimeFormat returns the default time format.
+// DefaultTimeFormat returns the default time format in the local time zone.
 func DefaultTimeFormat(t time.Time) []byte {
-	t = t.Local()
+	return formatDefaultTime(t.Local())
+}
+
+// formatDefaultTime returns the default time format in the time zone of t.
+func formatDefaultTime(t time.Time) []byte {
 	year, month, day := t.Date()
 	hour, minute, second := t.Clock()
 	micros := t.Nanosecond() / 1000
@@ -293,6 +478,36 @@ func (z *ZapLogWrapper) Fatalf(format string, args ...interface{}) {
 	z.l.Fatalf(format, args...)
 }
 
+// Tracew logs a message to TRACE log with some additional context.
+func (z *ZapLogWrapper) Tracew(msg string, keysAndValues ...interface{}) {
+	z.l.Tracew(msg, keysAndValues...)
+}
+
+// Debugw logs a message to DEBUG log with some additional context.
+func (z *ZapLogWrapper) Debugw(msg string, keysAndValues ...interface{}) {
+	z.l.Debugw(msg, keysAndValues...)
+}
+
+// Infow logs a message to INFO log with some additional context.
+func (z *ZapLogWrapper) Infow(msg string, keysAndValues ...interface{}) {
+	z.l.Infow(msg, keysAndValues...)
+}
+
+// Warnw logs a message to WARNING log with some additional context.
+func (z *ZapLogWrapper) Warnw(msg string, keysAndValues ...interface{}) {
+	z.l.Warnw(msg, keysAndValues...)
+}
+
+// Errorw logs a message to ERROR log with some additional context.
+func (z *ZapLogWrapper) Errorw(msg string, keysAndValues ...interface{}) {
+	z.l.Errorw(msg, keysAndValues...)
+}
+
+// Fatalw logs a message to FATAL log with some additional context.
+func (z *ZapLogWrapper) Fatalw(msg string, keysAndValues ...interface{}) {
+	z.l.Fatalw(msg, keysAndValues...)
+}
+
 // Sync calls the zap logger's Sync method, and flushes any buffered log entries.
 // Applications should take care to call Sync before exiting.
 func (z *ZapLogWrapper) Sync() error {
@@ -304,6 +519,11 @@ func (z *ZapLogWrapper) SetLevel(output string, level Level) {
 	z.l.SetLevel(output, level)
 }
 
+// SetLevelE sets output log level, and returns an error if the output or level does not exist.
+func (z *ZapLogWrapper) SetLevelE(output string, level Level) error {
+	return z.l.SetLevelE(output, level)
+}
+
 // GetLevel gets output log level.
 func (z *ZapLogWrapper) GetLevel(output string) Level {
 	return z.l.GetLevel(output)
@@ -325,10 +545,35 @@ func (z *ZapLogWrapper) With(fields ...Field) Logger {
 
 // zapLog is a Logger implementation based on zaplogger.
 type zapLog struct {
-	levels []zap.AtomicLevel
+	// cores holds the *zapCores shared by all Loggers derived from the same one. Reload swaps it.
+	cores  *atomic.Value
 	logger *zap.Logger
 }
 
+// outputs returns the current outputs of the Logger.
+func (l *zapLog) outputs() []zapOutput {
+	return l.cores.Load().(*zapCores).outputs
+}
+
+// toZapLog returns the underlying zapLog of l, or nil if l is not based on zapLog.
+func toZapLog(l Logger) *zapLog {
+	switch v := l.(type) {
+	case *zapLog:
+		return v
+	case *ZapLogWrapper:
+		return v.l
+	default:
+		return nil
+	}
+}
+
+// zapOutput describes one output of zapLog.
+type zapOutput struct {
+	name   string
+	writer string
+	level  zap.AtomicLevel
+}
+
 // WithFields set some user defined data to logs, such as uid, imei, etc.
 // Use this function at the beginning of each request. The returned new Logger should be used to
 // print logs.
@@ -344,7 +589,7 @@ func (l *zapLog) WithFields(fields ...string) Logger {
 	// caller information can be set correctly.
 	return &ZapLogWrapper{
 		l: &zapLog{
-			levels: l.levels,
+			cores:  l.cores,
 			logger: l.logger.With(zapFields...)}}
 }
 
@@ -359,10 +604,26 @@ func (l *zapLog) With(fields ...Field) Logger {
 	// caller information can be set correctly.
 	return &ZapLogWrapper{
 		l: &zapLog{
-			levels: l.levels,
+			cores:  l.cores,
 			logger: l.logger.With(zapFields...)}}
 }
 
+// Named returns a Logger whose name is joined to the name of l by a period, like zap.Logger.Named.
+// The name is written under name_key of the formatter, and matched by the logger of route rules.
+// l itself is returned if it is not created by NewZapLog.
+func Named(l Logger, name string) Logger {
+	z := toZapLog(l)
+	if z == nil {
+		return l
+	}
+	// By ZapLogWrapper proxy, we can add a layer to the debug series function calls, so that the
+	// caller information can be set correctly.
+	return &ZapLogWrapper{
+		l: &zapLog{
+			cores:  z.cores,
+			logger: z.logger.Named(name)}}
+}
+
 func getLogMsg(args ...interface{}) string {
 	msg := fmt.Sprint(args...)
 	return msg
@@ -373,17 +634,57 @@ func getLogMsgf(format string, args ...interface{}) string {
 	return msg
 }
 
+// getLogFields turns loosely typed key-value pairs into zap fields in the manner of zap's
+// SugaredLogger. zap.Field and Field arguments are used as they are. Instead of panicking, a key
+// without a value or a key which is not a string is reported by an error field.
+func getLogFields(keysAndValues []interface{}) []zap.Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+	var (
+		fields  = make([]zap.Field, 0, len(keysAndValues)/2+1)
+		invalid []string
+	)
+	for i := 0; i < len(keysAndValues); {
+		switch f := keysAndValues[i].(type) {
+		case zap.Field:
+			fields = append(fields, f)
+			i++
+			continue
+		case Field:
+			fields = append(fields, zap.Any(f.Key, f.Value))
+			i++
+			continue
+		}
+		if i == len(keysAndValues)-1 {
+			invalid = append(invalid, fmt.Sprintf("ignored key without a value: %v", keysAndValues[i]))
+			break
+		}
+		key, val := keysAndValues[i], keysAndValues[i+1]
+		if k, ok := key.(string); ok {
+			fields = append(fields, zap.Any(k, val))
+		} else {
+			invalid = append(invalid, fmt.Sprintf("ignored key of type %T: %v", key, key))
+		}
+		i += 2
+	}
+	if len(invalid) > 0 {
+		fields = append(fields, zap.Error(errors.New(strings.Join(invalid, "; "))))
+	}
+	return fields
+}
+
 // Trace logs to TRACE log. Arguments are handled in the manner of fmt.Print.
 func (l *zapLog) Trace(args ...interface{}) {
-	if l.logger.Core().Enabled(zapcore.DebugLevel) {
-		l.logger.Debug(getLogMsg(args...))
+	if l.logger.Core().Enabled(ZapTraceLevel) {
+		l.logger.Check(ZapTraceLevel, getLogMsg(args...)).Write()
 	}
 }
 
 // Tracef logs to TRACE log. Arguments are handled in the manner of fmt.Printf.
 func (l *zapLog) Tracef(format string, args ...interface{}) {
-	if l.logger.Core().Enabled(zapcore.DebugLevel) {
-		l.logger.Debug(getLogMsgf(format, args...))
+	if l.logger.Core().Enabled(ZapTraceLevel) {
+		l.logger.Check(ZapTraceLevel, getLogMsgf(format, args...)).Write()
 	}
 }
 
@@ -457,32 +758,96 @@ func (l *zapLog) Fatalf(format string, args ...interface{}) {
 	}
 }
 
+// Tracew logs a message to TRACE log with some additional context.
+func (l *zapLog) Tracew(msg string, keysAndValues ...interface{}) {
+	if l.logger.Core().Enabled(ZapTraceLevel) {
+		l.logger.Check(ZapTraceLevel, msg).Write(getLogFields(keysAndValues)...)
+	}
+}
+
+// Debugw logs a message to DEBUG log with some additional context.
+func (l *zapLog) Debugw(msg string, keysAndValues ...interface{}) {
+	if l.logger.Core().Enabled(zapcore.DebugLevel) {
+		l.logger.Debug(msg, getLogFields(keysAndValues)...)
+	}
+}
+
+// Infow logs a message to INFO log with some additional context.
+func (l *zapLog) Infow(msg string, keysAndValues ...interface{}) {
+	if l.logger.Core().Enabled(zapcore.InfoLevel) {
+		l.logger.Info(msg, getLogFields(keysAndValues)...)
+	}
+}
+
+// Warnw logs a message to WARNING log with some additional context.
+func (l *zapLog) Warnw(msg string, keysAndValues ...interface{}) {
+	if l.logger.Core().Enabled(zapcore.WarnLevel) {
+		l.logger.Warn(msg, getLogFields(keysAndValues)...)
+	}
+}
+
+// Errorw logs a message to ERROR log with some additional context.
+func (l *zapLog) Errorw(msg string, keysAndValues ...interface{}) {
+	if l.logger.Core().Enabled(zapcore.ErrorLevel) {
+		l.logger.Error(msg, getLogFields(keysAndValues)...)
+	}
+}
+
+// Fatalw logs a message to FATAL log with some additional context.
+func (l *zapLog) Fatalw(msg string, keysAndValues ...interface{}) {
+	if l.logger.Core().Enabled(zapcore.FatalLevel) {
+		l.logger.Fatal(msg, getLogFields(keysAndValues)...)
+	}
+}
+
 // Sync calls the zap logger's Sync method, and flushes any buffered log entries.
 // Applications should take care to call Sync before exiting.
 func (l *zapLog) Sync() error {
 	return l.logger.Sync()
 }
 
-// SetLevel sets output log level.
+// SetLevel sets output log level. It does nothing if the output does not exist.
 func (l *zapLog) SetLevel(output string, level Level) {
-	i, e := strconv.Atoi(output)
-	if e != nil {
-		return
+	_ = l.SetLevelE(output, level)
+}
+
+// SetLevelE sets output log level, and returns an error if the output or level does not exist.
+func (l *zapLog) SetLevelE(output string, level Level) error {
+	outputs := l.outputs()
+	i, err := outputIndex(outputs, output)
+	if err != nil {
+		return err
 	}
-	if i < 0 || i >= len(l.levels) {
-		return
+	lvl, ok := levelToZapLevel[level]
+	if !ok {
+		return fmt.Errorf("log: level %d invalid", level)
 	}
-	l.levels[i].SetLevel(levelToZapLevel[level])
+	outputs[i].level.SetLevel(lvl)
+	return nil
 }
 
 // GetLevel gets output log level.
 func (l *zapLog) GetLevel(output string) Level {
-	i, e := strconv.Atoi(output)
-	if e != nil {
+	outputs := l.outputs()
+	i, err := outputIndex(outputs, output)
+	if err != nil {
 		return LevelDebug
 	}
-	if i < 0 || i >= len(l.levels) {
-		return LevelDebug
+	return zapLevelToLevel[outputs[i].level.Level()]
+}
+
+// outputIndex resolves output, which is either the configured name or the index of an output,
+// to the index of the output in outputs. The outputs should be loaded once by the caller, as
+// Reload may replace them at any time.
+func outputIndex(outputs []zapOutput, output string) (int, error) {
+	for i, o := range outputs {
+		if o.name != "" && o.name == output {
+			return i, nil
+		}
+	}
+	i, err := strconv.Atoi(output)
+	if err != nil || i < 0 || i >= len(outputs) {
+		return 0, fmt.Errorf("log: output %s not exist", output)
 	}
-	return zapLevelToLevel[l.levels[i].Level()]
+	return i, nil
 }
@@ -1,6 +1,7 @@
 package log_test
 
 import (
+	"bytes"
 	"errors"
 	log "github.com/hyperits/tlog"
 	"github.com/hyperits/tlog/plugin"
@@ -203,3 +204,134 @@ func TestWithFields(t *testing.T) {
 	assert.Equal(t, "with fields warning", entry.Message)
 	assert.Equal(t, []zapcore.Field{{Key: "abc", Type: zapcore.Int32Type, Integer: 123}}, entry.Context)
 }
+
+func TestTraceLevel(t *testing.T) {
+	core, ob := observer.New(log.ZapTraceLevel)
+	log.RegisterWriter("trace", &withFieldsWriter{core: core})
+	zl := log.NewZapLog([]log.OutputConfig{{Writer: "trace"}})
+
+	zl.Trace("trace")
+	zl.Tracef("trace %d", 1)
+	zl.Debug("debug")
+	assert.Equal(t, 3, ob.Len())
+	assert.Equal(t, log.ZapTraceLevel, ob.All()[0].Level)
+	assert.Equal(t, "trace 1", ob.All()[1].Message)
+	assert.Equal(t, zap.DebugLevel, ob.All()[2].Level)
+
+	logger := log.NewZapLog([]log.OutputConfig{{Writer: "console", Level: "trace"}})
+	assert.Equal(t, log.LevelTrace, logger.GetLevel("0"))
+	logger.SetLevel("0", log.LevelDebug)
+	assert.Equal(t, log.LevelDebug, logger.GetLevel("0"))
+	logger.SetLevel("0", log.LevelTrace)
+	assert.Equal(t, log.LevelTrace, logger.GetLevel("0"))
+}
+
+func TestCapitalLevelEncoder(t *testing.T) {
+	cfg := zapcore.EncoderConfig{LevelKey: "L", MessageKey: "M", EncodeLevel: log.CapitalLevelEncoder}
+	for _, enc := range []zapcore.Encoder{zapcore.NewConsoleEncoder(cfg), zapcore.NewJSONEncoder(cfg)} {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Level: log.ZapTraceLevel, Message: "m"}, nil)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(), "TRACE")
+		buf, err = enc.EncodeEntry(zapcore.Entry{Level: zapcore.DebugLevel, Message: "m"}, nil)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(), "DEBUG")
+	}
+}
+
+func TestStructuredLogging(t *testing.T) {
+	core, ob := observer.New(log.ZapTraceLevel)
+	log.RegisterWriter("structured", &withFieldsWriter{core: core})
+	zl := log.NewZapLog([]log.OutputConfig{{Writer: "structured"}})
+	l := zl.With(log.Field{Key: "service", Value: "demo"})
+
+	l.Tracew("trace", "uid", 1)
+	l.Debugw("debug", zap.String("cmd", "x"), "uid", 2)
+	l.Infow("info", "uid", 3, "cmd", "x")
+	l.Warnw("warn", log.Field{Key: "uid", Value: 4})
+	l.Errorw("error")
+
+	entries := ob.All()
+	assert.Equal(t, 5, len(entries))
+	assert.Equal(t, log.ZapTraceLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{"service": "demo", "uid": int64(1)}, entries[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{"service": "demo", "cmd": "x", "uid": int64(2)},
+		entries[1].ContextMap())
+	assert.Equal(t, map[string]interface{}{"service": "demo", "uid": int64(3), "cmd": "x"},
+		entries[2].ContextMap())
+	assert.Equal(t, map[string]interface{}{"service": "demo", "uid": int64(4)}, entries[3].ContextMap())
+	assert.Equal(t, zap.ErrorLevel, entries[4].Level)
+	assert.Equal(t, map[string]interface{}{"service": "demo"}, entries[4].ContextMap())
+}
+
+func TestStructuredLoggingInvalidPairs(t *testing.T) {
+	core, ob := observer.New(zap.DebugLevel)
+	log.RegisterWriter("structured", &withFieldsWriter{core: core})
+	zl := log.NewZapLog([]log.OutputConfig{{Writer: "structured"}})
+
+	assert.NotPanics(t, func() {
+		zl.Infow("odd", "uid", 1, "dangling")
+		zl.Infow("non-string key", 42, "v", "cmd", "x")
+	})
+	entries := ob.All()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, map[string]interface{}{"uid": int64(1), "error": "ignored key without a value: dangling"},
+		entries[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{"cmd": "x", "error": "ignored key of type int: 42"},
+		entries[1].ContextMap())
+}
+
+func TestSetLevelByName(t *testing.T) {
+	logger := log.NewZapLog([]log.OutputConfig{
+		{Writer: "console", Level: "debug"},
+		{Name: "errors", Writer: "console", Level: "error"},
+	})
+	assert.Equal(t, log.LevelError, logger.GetLevel("errors"))
+	assert.Equal(t, log.LevelError, logger.GetLevel("1"))
+
+	assert.Nil(t, logger.SetLevelE("errors", log.LevelWarn))
+	assert.Equal(t, log.LevelWarn, logger.GetLevel("1"))
+	assert.Nil(t, logger.SetLevelE("0", log.LevelInfo))
+	assert.Equal(t, log.LevelInfo, logger.GetLevel("0"))
+
+	l := logger.With(log.Field{Key: "k", Value: "v"})
+	l.SetLevel("errors", log.LevelFatal)
+	assert.Equal(t, log.LevelFatal, logger.GetLevel("errors"))
+
+	assert.NotNil(t, logger.SetLevelE("not-exist", log.LevelInfo))
+	assert.NotNil(t, logger.SetLevelE("2", log.LevelInfo))
+	assert.NotNil(t, logger.SetLevelE("errors", log.LevelNil))
+
+	assert.Panics(t, func() {
+		log.NewZapLog([]log.OutputConfig{
+			{Name: "dup", Writer: "console"},
+			{Name: "dup", Writer: "console"},
+		})
+	})
+}
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger(
+		log.WithWriter(&buf),
+		log.WithLogLevel(log.LevelInfo),
+		log.WithPattern("%level %caller %msg %fields"),
+	)
+	logger.Debug("debug")
+	logger.With(log.Field{Key: "k", Value: "v"}).Info("info")
+	assert.Regexp(t, `^INFO \w+/zaplogger_test.go:\d+ info k=v\n$`, buf.String())
+	assert.Equal(t, log.LevelInfo, logger.GetLevel("0"))
+
+	buf.Reset()
+	logger = log.NewLogger(log.WithWriter(&buf), log.WithFormatter("json"))
+	logger.Debug("debug")
+	assert.Contains(t, buf.String(), `"M":"debug"`)
+
+	stdout, _ := redirectStd(t, func() {
+		log.NewLogger(log.WithPattern("%msg")).Info("console")
+	})
+	assert.Equal(t, "console\n", stdout)
+
+	assert.Panics(t, func() {
+		log.NewLogger(log.WithWriter(&buf), log.WithFormatter("unknown"))
+	})
+}